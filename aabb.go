@@ -52,6 +52,10 @@ func (bb *aabb) containsPoint(point Vector) bool {
 
 // collidesShape returns true if the AABB
 // overlaps the given shape, and false otherwise.
+//
+// The user-defined shapes are tested with the Rectangle
+// collision function registered for their type. If there's
+// no such function, the bounding box of the shape is tested.
 func (bb *aabb) collidesShape(shape Shape) (bool, error) {
 	switch other := shape.(type) {
 	case *Rectangle:
@@ -63,8 +67,20 @@ func (bb *aabb) collidesShape(shape Shape) (bool, error) {
 	case *Line:
 		return bb.collidesLine(other)
 
+	case *Polygon:
+		return bb.collidesPolygon(other)
+
+	case *Capsule:
+		return bb.collidesCapsule(other)
+
 	default:
-		return false, fmt.Errorf("shape type is unknown")
+		if !isCollisionRegistered("Rectangle", shape.TypeName()) {
+			min, max := shape.GetBoundingBox()
+
+			return bb.collidesAABB(&aabb{min: min, max: max})
+		}
+
+		return registeredCollision(bb.toRectangle(), shape)
 	}
 }
 
@@ -96,6 +112,31 @@ func (bb *aabb) collidesRectangle(rect *Rectangle) (bool, error) {
 	return CollisionRectangleToRectangle(bbRect, rect)
 }
 
+// collidesPolygon returns true if the given AABB
+// overlaps the given polygon, and false otherwise.
+func (bb *aabb) collidesPolygon(polygon *Polygon) (bool, error) {
+	if polygon == nil {
+		return false, fmt.Errorf("the polygon is nil")
+	}
+
+	vertices := bb.vertices()
+
+	return verticesOverlap(vertices[:], polygon.Vertices()), nil
+}
+
+// collidesCapsule returns true if the given AABB
+// overlaps the given capsule, and false otherwise.
+func (bb *aabb) collidesCapsule(capsule *Capsule) (bool, error) {
+	if capsule == nil {
+		return false, fmt.Errorf("the capsule is nil")
+	}
+
+	vertices := bb.vertices()
+	distance, _, _ := verticesDistance(vertices[:], capsule.segment())
+
+	return distance <= capsule.radius, nil
+}
+
 // collidesLine returns true if the given AABB
 // overlaps the given line, and false otherwise.
 func (bb *aabb) collidesLine(line *Line) (bool, error) {
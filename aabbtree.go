@@ -0,0 +1,394 @@
+package cirno
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// aabbTreeNode is a node of the dynamic AABB tree.
+// Leaves contain shapes, branches contain
+// the AABBs covering their children.
+type aabbTreeNode struct {
+	box    *aabb
+	parent *aabbTreeNode
+	left   *aabbTreeNode
+	right  *aabbTreeNode
+	shape  Shape
+	id     int
+}
+
+// isLeaf returns true if the node has no children.
+func (node *aabbTreeNode) isLeaf() bool {
+	return node.left == nil
+}
+
+// nodeStacks are the stacks of nodes
+// reused to traverse the AABB trees.
+var nodeStacks = sync.Pool{
+	New: func() interface{} {
+		return new([]*aabbTreeNode)
+	},
+}
+
+// aabbTree is a dynamic bounding volume hierarchy
+// of the shapes' bounding boxes. The boxes are fattened
+// by the margin, so the shapes moving a little
+// don't require the tree to be updated.
+type aabbTree struct {
+	root   *aabbTreeNode
+	leaves map[Shape]*aabbTreeNode
+	margin float64
+	nextID int
+}
+
+// Insert inserts the shape into the tree.
+// If the shape is already in the tree, it's updated.
+func (tree *aabbTree) Insert(shape Shape) error {
+	if shape == nil {
+		return fmt.Errorf("the shape is nil")
+	}
+
+	if _, ok := tree.leaves[shape]; ok {
+		return tree.Update(shape)
+	}
+
+	leaf := &aabbTreeNode{
+		box:   boundingBoxOf(shape).expanded(tree.margin),
+		shape: shape,
+		id:    tree.nextID,
+	}
+	tree.nextID++
+	tree.leaves[shape] = leaf
+	tree.insertLeaf(leaf)
+
+	return nil
+}
+
+// Remove removes the shape from the tree.
+func (tree *aabbTree) Remove(shape Shape) error {
+	if shape == nil {
+		return fmt.Errorf("the shape is nil")
+	}
+
+	leaf, ok := tree.leaves[shape]
+
+	if !ok {
+		return nil
+	}
+
+	tree.removeLeaf(leaf)
+	delete(tree.leaves, shape)
+
+	return nil
+}
+
+// Update moves the leaf of the shape if the shape
+// is not covered by its fat AABB anymore.
+func (tree *aabbTree) Update(shape Shape) error {
+	if shape == nil {
+		return fmt.Errorf("the shape is nil")
+	}
+
+	leaf, ok := tree.leaves[shape]
+
+	if !ok {
+		return fmt.Errorf("the tree doesn't contain the shape")
+	}
+
+	box := boundingBoxOf(shape)
+
+	if leaf.box.containsAABB(box) {
+		return nil
+	}
+
+	tree.removeLeaf(leaf)
+	leaf.box = box.expanded(tree.margin)
+	tree.insertLeaf(leaf)
+
+	return nil
+}
+
+// QueryAABB returns all the shapes whose fat
+// AABBs overlap the specified box.
+func (tree *aabbTree) QueryAABB(min, max Vector) (Shapes, error) {
+	box := &aabb{min: min, max: max}
+	shapes := make(Shapes, 0)
+
+	tree.query(box.overlaps, func(leaf *aabbTreeNode) error {
+		shapes.Insert(leaf.shape)
+
+		return nil
+	})
+
+	return shapes, nil
+}
+
+// QueryRay returns all the shapes whose fat AABBs
+// are crossed by the segment from one point to another.
+func (tree *aabbTree) QueryRay(from, to Vector) (Shapes, error) {
+	shapes := make(Shapes, 0)
+
+	tree.query(func(box *aabb) bool {
+		return box.collidesSegment(from, to)
+	}, func(leaf *aabbTreeNode) error {
+		shapes.Insert(leaf.shape)
+
+		return nil
+	})
+
+	return shapes, nil
+}
+
+// visitAABB calls the function for every shape
+// whose fat AABB overlaps the specified box.
+func (tree *aabbTree) visitAABB(min, max Vector, fn func(shape Shape) error) error {
+	box := aabb{min: min, max: max}
+
+	return tree.query(box.overlaps, func(leaf *aabbTreeNode) error {
+		return fn(leaf.shape)
+	})
+}
+
+// Pairs calls the function for every pair
+// of shapes whose fat AABBs overlap.
+func (tree *aabbTree) Pairs(fn func(one, other Shape) error) error {
+	for _, leaf := range tree.leaves {
+		err := tree.query(leaf.box.overlaps, func(other *aabbTreeNode) error {
+			// Every pair is reported by
+			// the leaf inserted first.
+			if other.id <= leaf.id {
+				return nil
+			}
+
+			return fn(leaf.shape, other.shape)
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Rebuild builds the balanced tree
+// out of all the leaves.
+func (tree *aabbTree) Rebuild() error {
+	leaves := make([]*aabbTreeNode, 0, len(tree.leaves))
+
+	for _, leaf := range tree.leaves {
+		leaf.parent = nil
+		leaves = append(leaves, leaf)
+	}
+
+	tree.root = buildAABBTree(leaves)
+
+	return nil
+}
+
+// Clear removes all the shapes from the tree.
+func (tree *aabbTree) Clear() error {
+	tree.root = nil
+	tree.leaves = map[Shape]*aabbTreeNode{}
+
+	return nil
+}
+
+// query calls the function for every leaf whose AABB
+// the overlaps function returns true for.
+func (tree *aabbTree) query(overlaps func(box *aabb) bool, fn func(leaf *aabbTreeNode) error) error {
+	if tree.root == nil {
+		return nil
+	}
+
+	buffer := nodeStacks.Get().(*[]*aabbTreeNode)
+	stack := append((*buffer)[:0], tree.root)
+
+	defer func() {
+		*buffer = stack[:0]
+		nodeStacks.Put(buffer)
+	}()
+
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if !overlaps(node.box) {
+			continue
+		}
+
+		if !node.isLeaf() {
+			stack = append(stack, node.left, node.right)
+
+			continue
+		}
+
+		if err := fn(node); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// insertLeaf finds the best sibling for the leaf
+// and inserts the leaf next to it.
+func (tree *aabbTree) insertLeaf(leaf *aabbTreeNode) {
+	if tree.root == nil {
+		tree.root = leaf
+		leaf.parent = nil
+
+		return
+	}
+
+	// Descend the tree choosing the child whose
+	// perimeter grows less after adding the leaf.
+	sibling := tree.root
+
+	for !sibling.isLeaf() {
+		combined := sibling.box.union(leaf.box)
+		cost := combined.perimeter()
+		inheritance := combined.perimeter() - sibling.box.perimeter()
+		leftCost := leaf.box.union(sibling.left.box).perimeter() + inheritance
+		rightCost := leaf.box.union(sibling.right.box).perimeter() + inheritance
+
+		if !sibling.left.isLeaf() {
+			leftCost -= sibling.left.box.perimeter()
+		}
+
+		if !sibling.right.isLeaf() {
+			rightCost -= sibling.right.box.perimeter()
+		}
+
+		if cost < leftCost && cost < rightCost {
+			break
+		}
+
+		if leftCost < rightCost {
+			sibling = sibling.left
+		} else {
+			sibling = sibling.right
+		}
+	}
+
+	oldParent := sibling.parent
+	newParent := &aabbTreeNode{
+		box:    sibling.box.union(leaf.box),
+		parent: oldParent,
+		left:   sibling,
+		right:  leaf,
+	}
+	sibling.parent = newParent
+	leaf.parent = newParent
+
+	if oldParent == nil {
+		tree.root = newParent
+	} else if oldParent.left == sibling {
+		oldParent.left = newParent
+	} else {
+		oldParent.right = newParent
+	}
+
+	tree.refit(oldParent)
+}
+
+// removeLeaf removes the leaf from the tree
+// and puts its sibling in place of its parent.
+func (tree *aabbTree) removeLeaf(leaf *aabbTreeNode) {
+	if leaf == tree.root {
+		tree.root = nil
+
+		return
+	}
+
+	parent := leaf.parent
+	grandParent := parent.parent
+	sibling := parent.left
+
+	if sibling == leaf {
+		sibling = parent.right
+	}
+
+	leaf.parent = nil
+	sibling.parent = grandParent
+
+	if grandParent == nil {
+		tree.root = sibling
+
+		return
+	}
+
+	if grandParent.left == parent {
+		grandParent.left = sibling
+	} else {
+		grandParent.right = sibling
+	}
+
+	tree.refit(grandParent)
+}
+
+// refit recomputes the AABBs of the node
+// and all its ancestors.
+func (tree *aabbTree) refit(node *aabbTreeNode) {
+	for ; node != nil; node = node.parent {
+		node.box = node.left.box.union(node.right.box)
+	}
+}
+
+// buildAABBTree builds the balanced subtree out of the leaves
+// splitting them by the median along the longest axis.
+func buildAABBTree(leaves []*aabbTreeNode) *aabbTreeNode {
+	switch len(leaves) {
+	case 0:
+		return nil
+
+	case 1:
+		return leaves[0]
+	}
+
+	box := leaves[0].box
+
+	for _, leaf := range leaves[1:] {
+		box = box.union(leaf.box)
+	}
+
+	horizontal := box.max.X-box.min.X >= box.max.Y-box.min.Y
+
+	sort.Slice(leaves, func(i, j int) bool {
+		a := leaves[i].box.center()
+		b := leaves[j].box.center()
+
+		if horizontal {
+			return a.X < b.X
+		}
+
+		return a.Y < b.Y
+	})
+
+	middle := len(leaves) / 2
+	node := &aabbTreeNode{
+		box:   box,
+		left:  buildAABBTree(leaves[:middle]),
+		right: buildAABBTree(leaves[middle:]),
+	}
+	node.left.parent = node
+	node.right.parent = node
+
+	return node
+}
+
+// NewAABBTree returns a new broadphase based on the dynamic
+// AABB tree. The bounding boxes of the shapes are extended by
+// the margin, so the shapes moving within it don't cause
+// the tree to be restructured.
+func NewAABBTree(margin float64) (Broadphase, error) {
+	if margin < 0 {
+		return nil, fmt.Errorf("the margin must be non-negative")
+	}
+
+	return &aabbTree{
+		leaves: map[Shape]*aabbTreeNode{},
+		margin: margin,
+	}, nil
+}
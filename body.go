@@ -0,0 +1,204 @@
+package cirno
+
+import "fmt"
+
+// BodyKind determines how the shape is expected to move
+// and which shapes it's paired with for collision detection.
+type BodyKind int
+
+const (
+	// BodyDynamic shapes move and collide all the other shapes.
+	BodyDynamic BodyKind = iota
+	// BodyKinematic shapes move, but they are paired
+	// only with dynamic shapes.
+	BodyKinematic
+	// BodyStatic shapes never move and are paired only
+	// with dynamic shapes. They are kept in the separate
+	// index which is rebuilt only on demand.
+	BodyStatic
+)
+
+// WithStaticBroadphase makes the space keep the static shapes
+// in the specified broadphase instead of the AABB tree.
+func WithStaticBroadphase(broadphase Broadphase) SpaceOption {
+	return func(space *Space) error {
+		if broadphase == nil {
+			return fmt.Errorf("the broadphase is nil")
+		}
+
+		space.staticIndex = broadphase
+
+		return nil
+	}
+}
+
+// AddAs adds new shapes of the specified kind in the space.
+// The shapes already added to the space are skipped, so
+// a shape should be removed first to change its kind.
+func (space *Space) AddAs(kind BodyKind, shapes ...Shape) error {
+	if kind < BodyDynamic || kind > BodyStatic {
+		return fmt.Errorf("unknown body kind: %d", kind)
+	}
+
+	for _, shape := range shapes {
+		if shape == nil {
+			return fmt.Errorf("the shape is nil")
+		}
+
+		if _, ok := space.shapes[shape]; ok {
+			continue
+		}
+
+		err := space.enforceBounds(shape)
+
+		if err != nil {
+			return err
+		}
+
+		err = space.indexOf(kind).Insert(shape)
+
+		if err != nil {
+			return err
+		}
+
+		space.shapes.Insert(shape)
+		space.kinds[shape] = kind
+		space.nextID++
+		shape.setID(space.nextID)
+		space.ids[shape] = space.nextID
+		space.byID[space.nextID] = shape
+
+		if err := space.wakeEntered(shape, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// KindOf returns the kind of the shape in the space.
+func (space *Space) KindOf(shape Shape) (BodyKind, error) {
+	if shape == nil {
+		return BodyDynamic, fmt.Errorf("the shape is nil")
+	}
+
+	kind, ok := space.kinds[shape]
+
+	if !ok {
+		return BodyDynamic, fmt.Errorf(
+			"the space doesn't contain the given shape")
+	}
+
+	return kind, nil
+}
+
+// RebuildStatic rebuilds the index of
+// the static shapes to optimize it.
+func (space *Space) RebuildStatic() error {
+	return space.staticIndex.Rebuild()
+}
+
+// indexOf returns the index for the shapes of the kind.
+func (space *Space) indexOf(kind BodyKind) Broadphase {
+	if kind == BodyStatic {
+		return space.staticIndex
+	}
+
+	return space.index
+}
+
+// paired returns true if the shapes should be
+// checked against each other for collision.
+func (space *Space) paired(one, other Shape) bool {
+	if space.kinds[one] != BodyDynamic &&
+		space.kinds[other] != BodyDynamic {
+		return false
+	}
+
+	return space.awake(one) || space.awake(other)
+}
+
+// pairs calls the function for every pair of shapes
+// that might collide. The pairs of the shapes none of
+// which is dynamic are skipped.
+func (space *Space) pairs(fn func(one, other Shape) error) error {
+	err := space.index.Pairs(func(one, other Shape) error {
+		if !space.paired(one, other) {
+			return nil
+		}
+
+		return fn(one, other)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	// Pair the dynamic shapes with the static ones.
+	var shape Shape
+	pairStatic := func(other Shape) error {
+		return fn(shape, other)
+	}
+
+	for shape = range space.kinds {
+		if err := space.staticPairs(shape, pairStatic); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// staticPairs calls the function for every static shape
+// the shape might collide. Nothing is done unless the shape
+// is dynamic. The sleeping shapes are not checked
+// against the static ones.
+func (space *Space) staticPairs(shape Shape, fn func(other Shape) error) error {
+	if space.kinds[shape] != BodyDynamic || !space.awake(shape) {
+		return nil
+	}
+
+	min, max := shape.GetBoundingBox()
+
+	return visitAABB(space.staticIndex, min, max, fn)
+}
+
+// queryAABB returns the shapes of both the indices
+// that might overlap the specified box.
+func (space *Space) queryAABB(min, max Vector) (Shapes, error) {
+	shapes, err := space.index.QueryAABB(min, max)
+
+	if err != nil {
+		return nil, err
+	}
+
+	statics, err := space.staticIndex.QueryAABB(min, max)
+
+	if err != nil {
+		return nil, err
+	}
+
+	shapes.Merge(statics)
+
+	return shapes, nil
+}
+
+// queryRay returns the shapes of both the indices that might
+// be crossed by the segment from one point to another.
+func (space *Space) queryRay(from, to Vector) (Shapes, error) {
+	shapes, err := space.index.QueryRay(from, to)
+
+	if err != nil {
+		return nil, err
+	}
+
+	statics, err := space.staticIndex.QueryRay(from, to)
+
+	if err != nil {
+		return nil, err
+	}
+
+	shapes.Merge(statics)
+
+	return shapes, nil
+}
@@ -0,0 +1,74 @@
+package cirno_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zergon321/cirno"
+)
+
+func TestBodyKinds(t *testing.T) {
+	space, err := cirno.NewSpace(2, 4, 40, 40, cirno.NewVector(-20, -20),
+		cirno.NewVector(20, 20), false)
+	assert.Nil(t, err)
+
+	wall, err := cirno.NewRectangle(cirno.NewVector(0, 0), 10, 2, 0)
+	assert.Nil(t, err)
+	floor, err := cirno.NewRectangle(cirno.NewVector(0, 1), 10, 2, 0)
+	assert.Nil(t, err)
+	platform, err := cirno.NewRectangle(cirno.NewVector(3, 2), 4, 2, 0)
+	assert.Nil(t, err)
+	elevator, err := cirno.NewRectangle(cirno.NewVector(4, 2), 4, 2, 0)
+	assert.Nil(t, err)
+	ball, err := cirno.NewCircle(cirno.NewVector(-4, 1), 1)
+	assert.Nil(t, err)
+
+	err = space.AddAs(cirno.BodyStatic, wall, floor)
+	assert.Nil(t, err)
+	err = space.AddAs(cirno.BodyKinematic, platform, elevator)
+	assert.Nil(t, err)
+	err = space.Add(ball)
+	assert.Nil(t, err)
+	err = space.AddAs(cirno.BodyKind(10), ball)
+	assert.NotNil(t, err)
+
+	kind, err := space.KindOf(wall)
+	assert.Nil(t, err)
+	assert.Equal(t, cirno.BodyStatic, kind)
+	kind, err = space.KindOf(ball)
+	assert.Nil(t, err)
+	assert.Equal(t, cirno.BodyDynamic, kind)
+
+	// Static-static, static-kinematic and kinematic-kinematic
+	// pairs are never checked for collision.
+	shapes, err := space.CollidingShapes()
+	assert.Nil(t, err)
+	assert.Len(t, shapes, 3)
+	assert.Len(t, shapes[ball], 2)
+	assert.Contains(t, shapes[ball], wall)
+	assert.Contains(t, shapes[ball], floor)
+	assert.NotContains(t, shapes[platform], elevator)
+
+	// The static shapes are still found by the queries.
+	colliding, err := space.CollidingWith(ball)
+	assert.Nil(t, err)
+	assert.Len(t, colliding, 2)
+
+	hit, ok, err := space.RaycastHit(cirno.NewVector(0, 10),
+		cirno.Down(), 20, 0, false)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, floor, hit.Shape)
+
+	err = space.RebuildStatic()
+	assert.Nil(t, err)
+	err = space.Remove(wall)
+	assert.Nil(t, err)
+	_, err = space.KindOf(wall)
+	assert.NotNil(t, err)
+
+	shapes, err = space.CollidingShapes()
+	assert.Nil(t, err)
+	assert.Len(t, shapes[ball], 1)
+}
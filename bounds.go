@@ -0,0 +1,286 @@
+package cirno
+
+import (
+	"fmt"
+	"math"
+)
+
+// BoundsPolicy determines what the space does with
+// the shapes whose centers leave the space bounds.
+type BoundsPolicy int
+
+const (
+	// BoundsIgnore lets the shapes leave the bounds.
+	// AdjustShapePosition still clamps the shapes
+	// into the bounds. This is the default policy.
+	BoundsIgnore BoundsPolicy = iota
+	// BoundsClamp moves the shapes back
+	// to the closest point within the bounds.
+	BoundsClamp
+	// BoundsReject makes Add, Update and
+	// AdjustShapePosition return an error
+	// for the shapes out of bounds.
+	BoundsReject
+	// BoundsGrow extends the bounds and
+	// the index of the space to fit the shapes.
+	BoundsGrow
+	// BoundsWrap moves the shapes leaving
+	// the bounds to the opposite side.
+	BoundsWrap
+)
+
+// growableBroadphase is a broadphase
+// which covers a limited area.
+type growableBroadphase interface {
+	// grow extends the area covered by the
+	// broadphase to contain the specified box.
+	grow(min, max Vector) error
+	// area returns the area covered by the broadphase.
+	area() coveredArea
+	// clearArea removes all the shapes from the
+	// broadphase and makes it cover the area.
+	clearArea(area coveredArea) error
+}
+
+// coveredArea is the area covered by the growable
+// broadphase and the depth it can be subdivided to.
+type coveredArea struct {
+	min   Vector
+	max   Vector
+	depth int
+}
+
+// WithBoundsPolicy sets the policy for the
+// shapes leaving the bounds of the space.
+func WithBoundsPolicy(policy BoundsPolicy) SpaceOption {
+	return func(space *Space) error {
+		if policy < BoundsIgnore || policy > BoundsWrap {
+			return fmt.Errorf("unknown bounds policy: %d", policy)
+		}
+
+		space.boundsPolicy = policy
+
+		return nil
+	}
+}
+
+// BoundsPolicy returns the policy for the
+// shapes leaving the bounds of the space.
+func (space *Space) BoundsPolicy() BoundsPolicy {
+	return space.boundsPolicy
+}
+
+// enforceBounds applies the bounds policy to the shape
+// being added to the space or updated within it.
+func (space *Space) enforceBounds(shape Shape) error {
+	switch space.boundsPolicy {
+	case BoundsClamp:
+		space.clamp(shape)
+
+	case BoundsReject:
+		inBounds, err := space.InBounds(shape)
+
+		if err != nil {
+			return err
+		}
+
+		if !inBounds {
+			return fmt.Errorf("the shape is out of bounds")
+		}
+
+	case BoundsGrow:
+		return space.grow(shape)
+
+	case BoundsWrap:
+		space.wrap(shape)
+		space.extendWrapBox(shape)
+	}
+
+	return nil
+}
+
+// clamp moves the center of the shape
+// to the closest point within the bounds.
+func (space *Space) clamp(shape Shape) {
+	pos := shape.Center()
+
+	if pos.X < space.min.X {
+		pos = shape.SetPosition(NewVector(space.min.X, pos.Y))
+	}
+
+	if pos.Y < space.min.Y {
+		pos = shape.SetPosition(NewVector(pos.X, space.min.Y))
+	}
+
+	if pos.X > space.max.X {
+		pos = shape.SetPosition(NewVector(space.max.X, pos.Y))
+	}
+
+	if pos.Y > space.max.Y {
+		shape.SetPosition(NewVector(pos.X, space.max.Y))
+	}
+}
+
+// wrap moves the shape out of bounds
+// to the opposite side of the space.
+func (space *Space) wrap(shape Shape) {
+	pos := shape.Center()
+	wrapped := NewVector(
+		wrapCoordinate(pos.X, space.min.X, space.max.X),
+		wrapCoordinate(pos.Y, space.min.Y, space.max.Y))
+
+	if wrapped != pos {
+		shape.SetPosition(wrapped)
+	}
+}
+
+// grow extends the bounds of the space and
+// its index to fit the shape if it's out of bounds.
+func (space *Space) grow(shape Shape) error {
+	pos := shape.Center()
+	space.min = NewVector(math.Min(space.min.X, pos.X),
+		math.Min(space.min.Y, pos.Y))
+	space.max = NewVector(math.Max(space.max.X, pos.X),
+		math.Max(space.max.Y, pos.Y))
+
+	min, max := shape.GetBoundingBox()
+
+	for _, index := range []Broadphase{space.index, space.staticIndex} {
+		if index, ok := index.(growableBroadphase); ok {
+			if err := index.grow(min, max); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// wrapCoordinate maps the coordinate into
+// the range from min (inclusive) to max (exclusive).
+func wrapCoordinate(value, min, max float64) float64 {
+	if value >= min && value < max {
+		return value
+	}
+
+	size := max - min
+	offset := math.Mod(value-min, size)
+
+	if offset < 0 {
+		offset += size
+	}
+
+	return min + offset
+}
+
+// area returns the boundary of the quad
+// tree root and the max level of the tree.
+func (tree *quadTree) area() coveredArea {
+	return coveredArea{
+		min:   tree.root.boundary.min,
+		max:   tree.root.boundary.max,
+		depth: tree.maxLevel,
+	}
+}
+
+// clearArea removes all the shapes from the quad tree
+// and makes the root have the boundary of the area.
+func (tree *quadTree) clearArea(area coveredArea) error {
+	boundary, err := newAABB(area.min, area.max)
+
+	if err != nil {
+		return err
+	}
+
+	tree.root.boundary = boundary
+	tree.maxLevel = area.depth
+
+	return tree.clear()
+}
+
+// grow doubles the quad tree root in the direction
+// of the box until the root covers the box.
+func (tree *quadTree) grow(min, max Vector) error {
+	for !tree.root.boundary.containsPoint(min) ||
+		!tree.root.boundary.containsPoint(max) {
+		oldRoot := tree.root
+		boundary := oldRoot.boundary
+		size := boundary.max.Subtract(boundary.min)
+		newBoundary := &aabb{min: boundary.min, max: boundary.max}
+
+		// The old root becomes the east or the west quadrant
+		// and the north or the south quadrant of the new root.
+		west := min.X < boundary.min.X
+		south := min.Y < boundary.min.Y
+
+		if west {
+			newBoundary.min.X -= size.X
+		} else {
+			newBoundary.max.X += size.X
+		}
+
+		if south {
+			newBoundary.min.Y -= size.Y
+		} else {
+			newBoundary.max.Y += size.Y
+		}
+
+		newRoot := &quadTreeNode{
+			tree:     tree,
+			boundary: newBoundary,
+			level:    0,
+			shapes:   Shapes{},
+		}
+
+		if err := tree.addLeaf(newRoot); err != nil {
+			return err
+		}
+
+		if err := newRoot.split(); err != nil {
+			return err
+		}
+
+		var quadrant **quadTreeNode
+
+		switch {
+		case west && south:
+			quadrant = &newRoot.northEast
+
+		case west:
+			quadrant = &newRoot.southEast
+
+		case south:
+			quadrant = &newRoot.northWest
+
+		default:
+			quadrant = &newRoot.southWest
+		}
+
+		if err := tree.removeLeaf(*quadrant); err != nil {
+			return err
+		}
+
+		*quadrant = oldRoot
+		oldRoot.parent = newRoot
+
+		// All the old nodes are one level deeper now,
+		// so the tree can be one level deeper as well.
+		nodes := []*quadTreeNode{oldRoot}
+
+		for len(nodes) > 0 {
+			node := nodes[len(nodes)-1]
+			nodes = nodes[:len(nodes)-1]
+			node.level++
+
+			if node.northWest != nil {
+				nodes = append(nodes, node.northEast,
+					node.northWest, node.southEast, node.southWest)
+			}
+		}
+
+		tree.maxLevel++
+		tree.root = newRoot
+	}
+
+	return nil
+}
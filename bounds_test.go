@@ -0,0 +1,187 @@
+package cirno_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zergon321/cirno"
+)
+
+func TestBoundsPolicies(t *testing.T) {
+	clamped, err := cirno.NewSpace(2, 4, 40, 40, cirno.NewVector(0, 0),
+		cirno.NewVector(10, 10), false, cirno.WithBoundsPolicy(cirno.BoundsClamp))
+	assert.Nil(t, err)
+	circle, err := cirno.NewCircle(cirno.NewVector(15, 5), 1)
+	assert.Nil(t, err)
+	err = clamped.Add(circle)
+	assert.Nil(t, err)
+	assert.Equal(t, cirno.NewVector(10, 5), circle.Center())
+
+	rejecting, err := cirno.NewSpace(2, 4, 40, 40, cirno.NewVector(0, 0),
+		cirno.NewVector(10, 10), false, cirno.WithBoundsPolicy(cirno.BoundsReject))
+	assert.Nil(t, err)
+	circle, err = cirno.NewCircle(cirno.NewVector(15, 5), 1)
+	assert.Nil(t, err)
+	err = rejecting.Add(circle)
+	assert.NotNil(t, err)
+	circle.SetPosition(cirno.NewVector(5, 5))
+	err = rejecting.Add(circle)
+	assert.Nil(t, err)
+	circle.Move(cirno.NewVector(0, -6))
+	_, err = rejecting.Update(circle)
+	assert.NotNil(t, err)
+
+	wrapping, err := cirno.NewSpace(2, 4, 40, 40, cirno.NewVector(0, 0),
+		cirno.NewVector(10, 10), false, cirno.WithBoundsPolicy(cirno.BoundsWrap))
+	assert.Nil(t, err)
+	circle, err = cirno.NewCircle(cirno.NewVector(5, 5), 1)
+	assert.Nil(t, err)
+	err = wrapping.Add(circle)
+	assert.Nil(t, err)
+	circle.Move(cirno.NewVector(7, -6))
+	_, err = wrapping.Update(circle)
+	assert.Nil(t, err)
+	assert.True(t, circle.Center().ApproximatelyEqual(cirno.NewVector(2, 9)))
+
+	_, err = cirno.NewSpace(2, 4, 40, 40, cirno.NewVector(0, 0),
+		cirno.NewVector(10, 10), false, cirno.WithBoundsPolicy(cirno.BoundsPolicy(10)))
+	assert.NotNil(t, err)
+}
+
+func TestBoundsGrow(t *testing.T) {
+	space, err := cirno.NewSpace(2, 1, 20, 20, cirno.NewVector(0, 0),
+		cirno.NewVector(10, 10), false, cirno.WithBoundsPolicy(cirno.BoundsGrow))
+	assert.Nil(t, err)
+
+	home, err := cirno.NewCircle(cirno.NewVector(5, 5), 1)
+	assert.Nil(t, err)
+	explorer, err := cirno.NewCircle(cirno.NewVector(8, 5), 1)
+	assert.Nil(t, err)
+	err = space.Add(home, explorer)
+	assert.Nil(t, err)
+
+	explorer.SetPosition(cirno.NewVector(100, -70))
+	_, err = space.Update(explorer)
+	assert.Nil(t, err)
+	assert.Equal(t, cirno.NewVector(0, -70), space.Min())
+	assert.Equal(t, cirno.NewVector(100, 10), space.Max())
+
+	camp, err := cirno.NewCircle(cirno.NewVector(101, -70), 1)
+	assert.Nil(t, err)
+	err = space.Add(camp)
+	assert.Nil(t, err)
+
+	shapes, err := space.CollidingWith(explorer)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(shapes))
+	assert.Contains(t, shapes, camp)
+
+	shape, _, err := space.Raycast(cirno.NewVector(90, -70),
+		cirno.Right(), 0, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, explorer, shape)
+
+	err = space.Rebuild()
+	assert.Nil(t, err)
+	shapes, err = space.Circlecast(home)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(shapes))
+}
+
+func TestBoundsOffOrigin(t *testing.T) {
+	policies := []cirno.BoundsPolicy{cirno.BoundsClamp,
+		cirno.BoundsReject, cirno.BoundsWrap}
+
+	for _, policy := range policies {
+		space, err := cirno.NewSpace(3, 1, 100, 100, cirno.NewVector(0, 0),
+			cirno.NewVector(100, 100), false, cirno.WithBoundsPolicy(policy))
+		assert.Nil(t, err)
+
+		circle, err := cirno.NewCircle(cirno.NewVector(75, 75), 2)
+		assert.Nil(t, err)
+		other, err := cirno.NewCircle(cirno.NewVector(77, 75), 2)
+		assert.Nil(t, err)
+		err = space.Add(circle, other)
+		assert.Nil(t, err)
+
+		circle.Move(cirno.NewVector(20, 20))
+		_, err = space.Update(circle)
+		assert.Nil(t, err)
+		circle.Move(cirno.NewVector(-20, -20))
+		_, err = space.Update(circle)
+		assert.Nil(t, err)
+
+		shapes, err := space.CollidingWith(circle)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(shapes))
+	}
+
+	// The shapes collide across
+	// the edges of the wrapped space.
+	wrapping, err := cirno.NewSpace(3, 1, 100, 100, cirno.NewVector(0, 0),
+		cirno.NewVector(100, 100), false, cirno.WithBoundsPolicy(cirno.BoundsWrap))
+	assert.Nil(t, err)
+	left, err := cirno.NewCircle(cirno.NewVector(1, 50), 2)
+	assert.Nil(t, err)
+	right, err := cirno.NewCircle(cirno.NewVector(98, 50), 2)
+	assert.Nil(t, err)
+	err = wrapping.Add(left, right)
+	assert.Nil(t, err)
+
+	shapes, err := wrapping.CollidingWith(left)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(shapes))
+}
+
+func TestBoundsQuadTreeRoot(t *testing.T) {
+	space, err := cirno.NewSpace(3, 4, 100, 100, cirno.NewVector(1000, 1000),
+		cirno.NewVector(1100, 1100), false, cirno.WithBoundsPolicy(cirno.BoundsReject))
+	assert.Nil(t, err)
+
+	circle, err := cirno.NewCircle(cirno.NewVector(1050, 1050), 2)
+	assert.Nil(t, err)
+	err = space.Add(circle)
+	assert.Nil(t, err)
+
+	// The root of the quad tree covers the bounds.
+	cells := space.Cells()
+	assert.Equal(t, 1, len(cells))
+
+	for cell := range cells {
+		assert.Equal(t, cirno.NewVector(1050, 1050), cell.Center())
+		assert.Equal(t, 100.0, cell.Width())
+		assert.Equal(t, 100.0, cell.Height())
+	}
+}
+
+func TestAdjustShapePosition(t *testing.T) {
+	expected := map[cirno.BoundsPolicy]cirno.Vector{
+		cirno.BoundsIgnore: cirno.NewVector(10, 5),
+		cirno.BoundsClamp:  cirno.NewVector(10, 5),
+		cirno.BoundsGrow:   cirno.NewVector(15, 5),
+		cirno.BoundsWrap:   cirno.NewVector(5, 5),
+	}
+
+	for policy, position := range expected {
+		space, err := cirno.NewSpace(2, 4, 10, 10, cirno.NewVector(0, 0),
+			cirno.NewVector(10, 10), false, cirno.WithBoundsPolicy(policy))
+		assert.Nil(t, err)
+		circle, err := cirno.NewCircle(cirno.NewVector(15, 5), 1)
+		assert.Nil(t, err)
+
+		err = space.AdjustShapePosition(circle)
+		assert.Nil(t, err)
+		assert.True(t, circle.Center().ApproximatelyEqual(position))
+	}
+
+	rejecting, err := cirno.NewSpace(2, 4, 10, 10, cirno.NewVector(0, 0),
+		cirno.NewVector(10, 10), false, cirno.WithBoundsPolicy(cirno.BoundsReject))
+	assert.Nil(t, err)
+	circle, err := cirno.NewCircle(cirno.NewVector(15, 5), 1)
+	assert.Nil(t, err)
+
+	err = rejecting.AdjustShapePosition(circle)
+	assert.NotNil(t, err)
+	assert.Equal(t, cirno.NewVector(15, 5), circle.Center())
+}
@@ -0,0 +1,207 @@
+package cirno
+
+import (
+	"fmt"
+	"math"
+	"sync"
+)
+
+// Broadphase is a spatial index of the shapes the space uses
+// to quickly find the shapes that might collide before the exact
+// collision detection is done. The shapes are indexed by their
+// bounding boxes, so the results of the queries are candidates
+// which should be checked for the actual collision.
+//
+// A broadphase must not be shared between spaces.
+type Broadphase interface {
+	// Insert adds the shape in the index.
+	Insert(shape Shape) error
+	// Remove removes the shape from the index.
+	Remove(shape Shape) error
+	// Update should be called on the shape
+	// whenever it's moved or rotated.
+	Update(shape Shape) error
+	// QueryAABB returns the shapes that might overlap the
+	// axis-aligned box specified by its min and max points.
+	QueryAABB(min, max Vector) (Shapes, error)
+	// QueryRay returns the shapes that might be crossed
+	// by the segment from one point to another.
+	QueryRay(from, to Vector) (Shapes, error)
+	// Pairs calls the function for every pair of shapes
+	// that might collide. Each pair is visited once.
+	// The iteration stops on the first error.
+	Pairs(fn func(one, other Shape) error) error
+	// Rebuild optimizes the index.
+	Rebuild() error
+	// Clear removes all the shapes from the index.
+	Clear() error
+}
+
+// cellBroadphase is a broadphase which
+// subdivides the space into cells.
+type cellBroadphase interface {
+	// cells returns all the cells of the
+	// index and the shapes within them.
+	cells() map[*aabb]Shapes
+	// shapeCells returns the shapes of the cells
+	// the shape is located in by the cell centers.
+	shapeCells(shape Shape) map[Vector]Shapes
+}
+
+// partitionedBroadphase is a broadphase whose pairs of shapes
+// can be found in several independent parts concurrently.
+type partitionedBroadphase interface {
+	// partitions splits the index into at most the specified
+	// number of parts and returns the function to find the pairs
+	// of shapes within each part. Every pair is found in one part.
+	partitions(count int) []func(fn func(one, other Shape) error) error
+}
+
+// visitingBroadphase is a broadphase which finds
+// the shapes without collecting them.
+type visitingBroadphase interface {
+	// visitAABB calls the function for every shape that
+	// might overlap the box specified by its min and max points.
+	visitAABB(min, max Vector, fn func(shape Shape) error) error
+}
+
+// visitAABB calls the function for every shape
+// of the index that might overlap the box.
+func visitAABB(index Broadphase, min, max Vector, fn func(shape Shape) error) error {
+	if index, ok := index.(visitingBroadphase); ok {
+		return index.visitAABB(min, max, fn)
+	}
+
+	shapes, err := index.QueryAABB(min, max)
+
+	if err != nil {
+		return err
+	}
+
+	for shape := range shapes {
+		if err := fn(shape); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// shapeBuffers are the lists of shapes reused
+// to find the pairs of shapes in the broadphases.
+var shapeBuffers = sync.Pool{
+	New: func() interface{} {
+		return new([]Shape)
+	},
+}
+
+// SpaceOption sets up the space created by NewSpace.
+type SpaceOption func(space *Space) error
+
+// WithBroadphase makes the space use the specified
+// broadphase instead of the quad tree.
+func WithBroadphase(broadphase Broadphase) SpaceOption {
+	return func(space *Space) error {
+		if broadphase == nil {
+			return fmt.Errorf("the broadphase is nil")
+		}
+
+		space.index = broadphase
+
+		return nil
+	}
+}
+
+// shapePair is an unordered pair of shapes.
+type shapePair struct {
+	one   Shape
+	other Shape
+}
+
+// boundingBoxOf returns the AABB of the shape.
+func boundingBoxOf(shape Shape) *aabb {
+	min, max := shape.GetBoundingBox()
+
+	return &aabb{min: min, max: max}
+}
+
+// union returns the AABB covering both AABBs.
+func (bb *aabb) union(other *aabb) *aabb {
+	return &aabb{
+		min: NewVector(math.Min(bb.min.X, other.min.X),
+			math.Min(bb.min.Y, other.min.Y)),
+		max: NewVector(math.Max(bb.max.X, other.max.X),
+			math.Max(bb.max.Y, other.max.Y)),
+	}
+}
+
+// containsAABB returns true if the other
+// AABB is completely inside the AABB.
+func (bb *aabb) containsAABB(other *aabb) bool {
+	return bb.containsPoint(other.min) &&
+		bb.containsPoint(other.max)
+}
+
+// perimeter returns the perimeter of the AABB.
+func (bb *aabb) perimeter() float64 {
+	return 2 * (bb.max.X - bb.min.X + bb.max.Y - bb.min.Y)
+}
+
+// expanded returns the AABB extended
+// by the margin in all the directions.
+func (bb *aabb) expanded(margin float64) *aabb {
+	extents := NewVector(margin, margin)
+
+	return &aabb{
+		min: bb.min.Subtract(extents),
+		max: bb.max.Add(extents),
+	}
+}
+
+// overlaps returns true if the AABBs overlap.
+func (bb *aabb) overlaps(other *aabb) bool {
+	return bb.min.X <= other.max.X &&
+		bb.max.X >= other.min.X &&
+		bb.min.Y <= other.max.Y &&
+		bb.max.Y >= other.min.Y
+}
+
+// collidesSegment returns true if the segment
+// from p to q crosses the AABB.
+func (bb *aabb) collidesSegment(p, q Vector) bool {
+	direction := q.Subtract(p)
+	tMin := 0.0
+	tMax := 1.0
+
+	for _, axis := range [2]struct {
+		origin, direction, min, max float64
+	}{
+		{p.X, direction.X, bb.min.X, bb.max.X},
+		{p.Y, direction.Y, bb.min.Y, bb.max.Y},
+	} {
+		// The segment is parallel to the slab.
+		if math.Abs(axis.direction) < Epsilon {
+			if axis.origin < axis.min || axis.origin > axis.max {
+				return false
+			}
+
+			continue
+		}
+
+		t1 := (axis.min - axis.origin) / axis.direction
+		t2 := (axis.max - axis.origin) / axis.direction
+
+		if t1 > t2 {
+			t1, t2 = t2, t1
+		}
+
+		tMin = math.Max(tMin, t1)
+		tMax = math.Min(tMax, t2)
+
+		if tMin > tMax {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,177 @@
+package cirno_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zergon321/cirno"
+)
+
+func TestBroadphases(t *testing.T) {
+	aabbTree, err := cirno.NewAABBTree(1)
+	assert.Nil(t, err)
+	spatialHash, err := cirno.NewSpatialHash(8)
+	assert.Nil(t, err)
+
+	broadphases := map[string][]cirno.SpaceOption{
+		"quadtree":        nil,
+		"aabb tree":       {cirno.WithBroadphase(aabbTree)},
+		"sweep and prune": {cirno.WithBroadphase(cirno.NewSweepAndPrune())},
+		"spatial hash":    {cirno.WithBroadphase(spatialHash)},
+	}
+
+	for name, options := range broadphases {
+		t.Run(name, func(t *testing.T) {
+			space, err := cirno.NewSpace(3, 2, 128, 128,
+				cirno.NewVector(0, 0), cirno.NewVector(64, 64), false, options...)
+			assert.Nil(t, err)
+
+			circle, err := cirno.NewCircle(cirno.NewVector(10, 10), 3)
+			assert.Nil(t, err)
+			rect, err := cirno.NewRectangle(cirno.NewVector(14, 10), 4, 4, 0)
+			assert.Nil(t, err)
+			line, err := cirno.NewLine(cirno.NewVector(2, 40), cirno.NewVector(60, 40))
+			assert.Nil(t, err)
+			other, err := cirno.NewCircle(cirno.NewVector(40, 20), 2)
+			assert.Nil(t, err)
+
+			err = space.Add(circle, rect, line, other)
+			assert.Nil(t, err)
+
+			collidingShapes, err := space.CollidingShapes()
+			assert.Nil(t, err)
+			assert.Equal(t, 2, len(collidingShapes))
+			assert.Contains(t, collidingShapes[circle], rect)
+			assert.Contains(t, collidingShapes[rect], circle)
+
+			shape, _, err := space.Raycast(cirno.NewVector(40, 2),
+				cirno.Up(), 0, 0)
+			assert.Nil(t, err)
+			assert.Equal(t, other, shape)
+
+			box, err := cirno.NewRectangle(cirno.NewVector(30, 40), 4, 4, 0)
+			assert.Nil(t, err)
+			shapes, err := space.Boxcast(box)
+			assert.Nil(t, err)
+			assert.Equal(t, 1, len(shapes))
+			assert.Contains(t, shapes, line)
+
+			// Move the circle to the line.
+			circle.SetPosition(cirno.NewVector(30, 38))
+			_, err = space.Update(circle)
+			assert.Nil(t, err)
+			err = space.Rebuild()
+			assert.Nil(t, err)
+
+			shapes, err = space.CollidingWith(circle)
+			assert.Nil(t, err)
+			assert.Equal(t, 1, len(shapes))
+			assert.Contains(t, shapes, line)
+
+			collidingShapes, err = space.CollidingShapes()
+			assert.Nil(t, err)
+			assert.Equal(t, 2, len(collidingShapes))
+			assert.Contains(t, collidingShapes[line], circle)
+
+			err = space.Remove(line)
+			assert.Nil(t, err)
+			collidingShapes, err = space.CollidingShapes()
+			assert.Nil(t, err)
+			assert.Equal(t, 0, len(collidingShapes))
+
+			err = space.Clear()
+			assert.Nil(t, err)
+			shapes, err = space.Circlecast(circle)
+			assert.Nil(t, err)
+			assert.Equal(t, 0, len(shapes))
+		})
+	}
+}
+
+func TestBroadphasesAgree(t *testing.T) {
+	aabbTree, err := cirno.NewAABBTree(0.5)
+	assert.Nil(t, err)
+	spatialHash, err := cirno.NewSpatialHash(5)
+	assert.Nil(t, err)
+	spaces := []*cirno.Space{}
+
+	for _, options := range [][]cirno.SpaceOption{
+		nil,
+		{cirno.WithBroadphase(aabbTree)},
+		{cirno.WithBroadphase(cirno.NewSweepAndPrune())},
+		{cirno.WithBroadphase(spatialHash)},
+	} {
+		space, err := cirno.NewSpace(5, 4, 256, 256,
+			cirno.NewVector(0, 0), cirno.NewVector(100, 100), false, options...)
+		assert.Nil(t, err)
+		spaces = append(spaces, space)
+	}
+
+	random := rand.New(rand.NewSource(321))
+	circles := []*cirno.Circle{}
+
+	for i := 0; i < 200; i++ {
+		circle, err := cirno.NewCircle(cirno.NewVector(random.Float64()*100,
+			random.Float64()*100), 1+random.Float64()*3)
+		assert.Nil(t, err)
+		circles = append(circles, circle)
+
+		for _, space := range spaces {
+			err = space.Add(circle)
+			assert.Nil(t, err)
+		}
+	}
+
+	for step := 0; step < 3; step++ {
+		for _, circle := range circles {
+			circle.Move(cirno.NewVector(random.Float64()*4-2,
+				random.Float64()*4-2))
+
+			for _, space := range spaces {
+				_, err = space.Update(circle)
+				assert.Nil(t, err)
+			}
+		}
+
+		expected, err := spaces[0].CollidingShapes()
+		assert.Nil(t, err)
+
+		for _, space := range spaces[1:] {
+			collidingShapes, err := space.CollidingShapes()
+			assert.Nil(t, err)
+			assert.Equal(t, expected, collidingShapes)
+		}
+	}
+}
+
+func TestSpatialHashUnbounded(t *testing.T) {
+	spatialHash, err := cirno.NewSpatialHash(4)
+	assert.Nil(t, err)
+	space, err := cirno.NewSpace(1, 1, 64, 64, cirno.NewVector(0, 0),
+		cirno.NewVector(64, 64), false, cirno.WithBroadphase(spatialHash))
+	assert.Nil(t, err)
+
+	far, err := cirno.NewCircle(cirno.NewVector(-10000, 5000), 2)
+	assert.Nil(t, err)
+	near, err := cirno.NewCircle(cirno.NewVector(-9997, 5000), 2)
+	assert.Nil(t, err)
+	err = space.Add(far, near)
+	assert.Nil(t, err)
+
+	cells, err := space.Update(far)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(cells))
+	assert.Equal(t, 6, len(space.Cells()))
+
+	collidingShapes, err := space.CollidingShapes()
+	assert.Nil(t, err)
+	assert.Contains(t, collidingShapes[far], near)
+
+	shape, hit, err := space.Raycast(cirno.NewVector(-10000, 4900),
+		cirno.Up(), 200, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, far, shape)
+	assert.InDelta(t, 4998, hit.Y, 0.0001)
+}
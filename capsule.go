@@ -0,0 +1,245 @@
+package cirno
+
+import (
+	"fmt"
+	"math"
+)
+
+// Capsule represents a line segment from p to q
+// swept by a circle of the given radius.
+type Capsule struct {
+	p      Vector
+	q      Vector
+	radius float64
+	angle  float64
+	tag
+	data
+	domain
+}
+
+// TypeName returns the name of the shape type.
+func (c *Capsule) TypeName() string {
+	return "Capsule"
+}
+
+// Center returns the coordinates of the middle point
+// between p and q.
+func (c *Capsule) Center() Vector {
+	return NewVector((c.q.X+c.p.X)/2.0, (c.q.Y+c.p.Y)/2.0)
+}
+
+// Angle returns the rotation angle of the capsule (in degrees).
+func (c *Capsule) Angle() float64 {
+	return c.angle
+}
+
+// AngleRadians returns the rotation angle of the capsule (in radians).
+func (c *Capsule) AngleRadians() float64 {
+	return c.angle * DegToRad
+}
+
+// P returns the starting point of the capsule segment.
+func (c *Capsule) P() Vector {
+	return c.p
+}
+
+// Q returns the ending point of the capsule segment.
+func (c *Capsule) Q() Vector {
+	return c.q
+}
+
+// Radius returns the radius of the capsule.
+func (c *Capsule) Radius() float64 {
+	return c.radius
+}
+
+// Length returns the length of the capsule segment.
+func (c *Capsule) Length() float64 {
+	return c.q.Subtract(c.p).Magnitude()
+}
+
+// Move moves the capsule in the specified direction
+// and returns its new position.
+func (c *Capsule) Move(direction Vector) Vector {
+	c.q = c.q.Add(direction)
+	c.p = c.p.Add(direction)
+
+	return c.Center()
+}
+
+// SetPosition sets the position of the capsule
+// to the given coordinates.
+func (c *Capsule) SetPosition(pos Vector) Vector {
+	direction := pos.Subtract(c.Center())
+
+	return c.Move(direction)
+}
+
+// SetAngle sets the rotation angle of the capsule
+// to the specified value (in degrees).
+func (c *Capsule) SetAngle(angle float64) float64 {
+	return c.Rotate(angle - c.angle)
+}
+
+// SetAngleRadians sets the rotation angle of the capsule
+// to the specified value (in radians).
+func (c *Capsule) SetAngleRadians(angle float64) float64 {
+	return c.RotateRadians(angle - c.AngleRadians())
+}
+
+// Rotate rotates the capsule at the
+// specified angle (in degrees).
+//
+// Returns the rotation angle of
+// the capsule (in degrees).
+func (c *Capsule) Rotate(angle float64) float64 {
+	center := c.Center()
+	pv := c.p.Subtract(center)
+	qv := c.q.Subtract(center)
+
+	pv = pv.Rotate(angle)
+	qv = qv.Rotate(angle)
+
+	c.p = center.Add(pv)
+	c.q = center.Add(qv)
+
+	c.angle += angle
+	c.angle = AdjustAngle(c.angle)
+
+	return c.angle
+}
+
+// RotateRadians rotates the capsule at the
+// specified angle (in radians).
+//
+// Returns the rotation angle of
+// the capsule (in radians).
+func (c *Capsule) RotateRadians(angle float64) float64 {
+	return c.Rotate(angle*RadToDeg) * DegToRad
+}
+
+// RotateAround rotates the capsule around the base point.
+func (c *Capsule) RotateAround(angle float64, base Vector) Vector {
+	center := c.Center()
+	cp := c.p.Subtract(center)
+	cq := c.q.Subtract(center)
+
+	center = center.RotateAround(angle, base)
+	c.p = center.Add(cp)
+	c.q = center.Add(cq)
+
+	return center
+}
+
+// RotateAroundRadians rotates the capsule around the base point at the
+// angle in radians.
+func (c *Capsule) RotateAroundRadians(angle float64, base Vector) Vector {
+	center := c.Center()
+	cp := c.p.Subtract(center)
+	cq := c.q.Subtract(center)
+
+	center = center.RotateAroundRadians(angle, base)
+	c.p = center.Add(cp)
+	c.q = center.Add(cq)
+
+	return center
+}
+
+// ContainsPoint detects if the point is inside the capsule.
+func (c *Capsule) ContainsPoint(point Vector) bool {
+	closestPoint := closestPointOnSegment(c.p, c.q, point)
+
+	return SquaredDistance(closestPoint, point) <= c.radius*c.radius
+}
+
+// GetBoundingBox returns the bounding box for the capsule.
+func (c *Capsule) GetBoundingBox() (Vector, Vector) {
+	min := NewVector(math.Min(c.p.X, c.q.X)-c.radius,
+		math.Min(c.p.Y, c.q.Y)-c.radius)
+	max := NewVector(math.Max(c.p.X, c.q.X)+c.radius,
+		math.Max(c.p.Y, c.q.Y)+c.radius)
+
+	return min, max
+}
+
+// segment returns the inner segment
+// of the capsule as a set of vertices.
+func (c *Capsule) segment() []Vector {
+	return []Vector{c.p, c.q}
+}
+
+// sides returns the straight parts
+// of the capsule outline.
+func (c *Capsule) sides() ([]*Line, error) {
+	direction, err := c.q.Subtract(c.p).Normalize()
+
+	if err != nil {
+		return nil, err
+	}
+
+	offset := direction.PerpendicularCounterClockwise().
+		MultiplyByScalar(c.radius)
+	left, err := NewLine(c.p.Add(offset), c.q.Add(offset))
+
+	if err != nil {
+		return nil, err
+	}
+
+	right, err := NewLine(c.p.Subtract(offset), c.q.Subtract(offset))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return []*Line{left, right}, nil
+}
+
+// caps returns the circles whose halves
+// form the round parts of the capsule outline.
+func (c *Capsule) caps() ([]*Circle, error) {
+	pCap, err := NewCircle(c.p, c.radius)
+
+	if err != nil {
+		return nil, err
+	}
+
+	qCap, err := NewCircle(c.q, c.radius)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return []*Circle{pCap, qCap}, nil
+}
+
+// onCap returns true if the point of the cap
+// circle belongs to the capsule outline.
+func (c *Capsule) onCap(point Vector) bool {
+	return Dot(point.Subtract(c.p), c.q.Subtract(c.p)) < 0 ||
+		Dot(point.Subtract(c.q), c.p.Subtract(c.q)) < 0
+}
+
+// NewCapsule returns a new capsule with the given parameters.
+func NewCapsule(p, q Vector, radius float64) (*Capsule, error) {
+	if Distance(p, q) < Epsilon {
+		return nil, fmt.Errorf(
+			"the length of the capsule segment must be positive")
+	}
+
+	if radius <= 0 {
+		return nil, fmt.Errorf(
+			"capsule radius must be positive, but got %f", radius)
+	}
+
+	capsule := &Capsule{
+		p:      p,
+		q:      q,
+		radius: radius,
+	}
+
+	pq := capsule.q.Subtract(capsule.p)
+	capsule.angle = AdjustAngle(math.Atan2(pq.Y, pq.X) * RadToDeg)
+	capsule.treeNodes = []*quadTreeNode{}
+
+	return capsule, nil
+}
@@ -0,0 +1,110 @@
+package cirno_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zergon321/cirno"
+)
+
+func TestCapsuleCollisions(t *testing.T) {
+	capsule, err := cirno.NewCapsule(cirno.NewVector(0, 0), cirno.NewVector(4, 0), 1)
+	assert.Nil(t, err)
+	c1, err := cirno.NewCircle(cirno.NewVector(6, 0), 1.5)
+	assert.Nil(t, err)
+	c2, err := cirno.NewCircle(cirno.NewVector(2, 3), 1.5)
+	assert.Nil(t, err)
+	l1, err := cirno.NewLine(cirno.NewVector(-2, 0.5), cirno.NewVector(-2, 5))
+	assert.Nil(t, err)
+	l2, err := cirno.NewLine(cirno.NewVector(-1.5, 0.5), cirno.NewVector(-0.5, -0.5))
+	assert.Nil(t, err)
+	r1, err := cirno.NewRectangle(cirno.NewVector(2, -2.5), 2, 2, 0)
+	assert.Nil(t, err)
+	r2, err := cirno.NewRectangle(cirno.NewVector(2, -2), 2, 2, 45)
+	assert.Nil(t, err)
+	other, err := cirno.NewCapsule(cirno.NewVector(5.5, -3), cirno.NewVector(5.5, 3), 0.4)
+	assert.Nil(t, err)
+
+	res0, err := cirno.ResolveCollision(capsule, c1, false)
+	assert.Nil(t, err)
+	res1, err := cirno.ResolveCollision(c2, capsule, false)
+	assert.Nil(t, err)
+	res2, err := cirno.ResolveCollision(l1, capsule, false)
+	assert.Nil(t, err)
+	res3, err := cirno.ResolveCollision(capsule, l2, false)
+	assert.Nil(t, err)
+	res4, err := cirno.ResolveCollision(capsule, r1, false)
+	assert.Nil(t, err)
+	res5, err := cirno.ResolveCollision(r2, capsule, false)
+	assert.Nil(t, err)
+	res6, err := cirno.ResolveCollision(capsule, other, false)
+	assert.Nil(t, err)
+
+	assert.True(t, res0)
+	assert.False(t, res1)
+	assert.False(t, res2)
+	assert.True(t, res3)
+	assert.False(t, res4)
+	assert.True(t, res5)
+	assert.False(t, res6)
+}
+
+func TestCapsuleRotation(t *testing.T) {
+	capsule, err := cirno.NewCapsule(cirno.NewVector(0, 0), cirno.NewVector(4, 0), 1)
+	assert.Nil(t, err)
+
+	capsule.Rotate(90)
+	assert.InDelta(t, 90, capsule.Angle(), 0.0001)
+	assert.True(t, capsule.P().ApproximatelyEqual(cirno.NewVector(2, -2)))
+	assert.True(t, capsule.Q().ApproximatelyEqual(cirno.NewVector(2, 2)))
+	assert.True(t, capsule.ContainsPoint(cirno.NewVector(2, 2.9)))
+	assert.False(t, capsule.ContainsPoint(cirno.NewVector(3.1, 0)))
+
+	center := capsule.RotateAround(90, cirno.Zero())
+	assert.True(t, center.ApproximatelyEqual(cirno.NewVector(0, 2)))
+	assert.InDelta(t, 90, capsule.Angle(), 0.0001)
+}
+
+func TestCapsuleContactsAndNormal(t *testing.T) {
+	capsule, err := cirno.NewCapsule(cirno.NewVector(0, 0), cirno.NewVector(4, 0), 1)
+	assert.Nil(t, err)
+	line, err := cirno.NewLine(cirno.NewVector(-3, 0), cirno.NewVector(7, 0))
+	assert.Nil(t, err)
+	circle, err := cirno.NewCircle(cirno.NewVector(2, 3), 1)
+	assert.Nil(t, err)
+
+	contacts, err := cirno.Contact(line, capsule)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(contacts))
+
+	normal, err := capsule.NormalTo(circle)
+	assert.Nil(t, err)
+	assert.True(t, normal.ApproximatelyEqual(cirno.Up()))
+
+	normal, err = circle.NormalTo(capsule)
+	assert.Nil(t, err)
+	assert.True(t, normal.ApproximatelyEqual(cirno.Down()))
+}
+
+func TestRaycastCapsule(t *testing.T) {
+	capsule, err := cirno.NewCapsule(cirno.NewVector(30, 20), cirno.NewVector(30, 40), 2)
+	assert.Nil(t, err)
+
+	space, err := cirno.NewSpace(1, 10, 64, 64,
+		cirno.NewVector(0, 0), cirno.NewVector(64, 64), false)
+	assert.Nil(t, err)
+	err = space.Add(capsule)
+	assert.Nil(t, err)
+
+	shape, hit, err := space.Raycast(cirno.NewVector(10, 30),
+		cirno.Right(), 0, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, capsule, shape)
+	assert.InDelta(t, 28, hit.X, 0.0001)
+
+	shape, hit, err = space.Raycast(cirno.NewVector(30, 10),
+		cirno.Up(), 0, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, capsule, shape)
+	assert.InDelta(t, 18, hit.Y, 0.0001)
+}
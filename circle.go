@@ -121,6 +121,13 @@ func (c *Circle) ContainsPoint(point Vector) bool {
 	return d.SquaredMagnitude() <= c.radius*c.radius
 }
 
+// GetBoundingBox returns the bounding box for the circle.
+func (c *Circle) GetBoundingBox() (Vector, Vector) {
+	extents := NewVector(c.radius, c.radius)
+
+	return c.center.Subtract(extents), c.center.Add(extents)
+}
+
 // NewCircle create a new circle with the given parameters.
 func NewCircle(position Vector, radius float64) (*Circle, error) {
 	if radius <= 0 {
@@ -26,6 +26,12 @@ func ResolveCollision(one, another Shape, useTags bool) (bool, error) {
 		return false, nil
 	}
 
+	// The user-defined shapes may have the
+	// type names of the built-in ones.
+	if !isBuiltin(one) || !isBuiltin(another) {
+		return registeredCollision(one, another)
+	}
+
 	id := one.TypeName() + "_" + another.TypeName()
 
 	switch id {
@@ -55,11 +61,57 @@ func ResolveCollision(one, another Shape, useTags bool) (bool, error) {
 
 	case "Rectangle_Line":
 		return IntersectionLineToRectangle(another.(*Line), one.(*Rectangle))
+
+	case "Polygon_Polygon":
+		return CollisionPolygonToPolygon(one.(*Polygon), another.(*Polygon))
+
+	case "Polygon_Rectangle":
+		return CollisionPolygonToRectangle(one.(*Polygon), another.(*Rectangle))
+
+	case "Rectangle_Polygon":
+		return CollisionPolygonToRectangle(another.(*Polygon), one.(*Rectangle))
+
+	case "Polygon_Circle":
+		return CollisionPolygonToCircle(one.(*Polygon), another.(*Circle))
+
+	case "Circle_Polygon":
+		return CollisionPolygonToCircle(another.(*Polygon), one.(*Circle))
+
+	case "Line_Polygon":
+		return IntersectionLineToPolygon(one.(*Line), another.(*Polygon))
+
+	case "Polygon_Line":
+		return IntersectionLineToPolygon(another.(*Line), one.(*Polygon))
+
+	case "Capsule_Capsule":
+		return CollisionCapsuleToCapsule(one.(*Capsule), another.(*Capsule))
+
+	case "Capsule_Circle":
+		return CollisionCapsuleToCircle(one.(*Capsule), another.(*Circle))
+
+	case "Circle_Capsule":
+		return CollisionCapsuleToCircle(another.(*Capsule), one.(*Circle))
+
+	case "Capsule_Line":
+		return IntersectionLineToCapsule(another.(*Line), one.(*Capsule))
+
+	case "Line_Capsule":
+		return IntersectionLineToCapsule(one.(*Line), another.(*Capsule))
+
+	case "Capsule_Rectangle":
+		return CollisionCapsuleToRectangle(one.(*Capsule), another.(*Rectangle))
+
+	case "Rectangle_Capsule":
+		return CollisionCapsuleToRectangle(another.(*Capsule), one.(*Rectangle))
+
+	case "Capsule_Polygon":
+		return CollisionCapsuleToPolygon(one.(*Capsule), another.(*Polygon))
+
+	case "Polygon_Capsule":
+		return CollisionCapsuleToPolygon(another.(*Capsule), one.(*Polygon))
 	}
 
-	return false, fmt.Errorf(
-		"unknown shape type combination: '%s' and '%s'",
-		one.TypeName(), another.TypeName())
+	return registeredCollision(one, another)
 }
 
 // CollisionRectangleToRectangle detects if there is an intersection
@@ -252,3 +304,153 @@ func IntersectionLineToRectangle(line *Line, rect *Rectangle) (bool, error) {
 
 	return true, nil
 }
+
+// CollisionPolygonToPolygon detects if there is an intersection
+// between two convex polygons.
+func CollisionPolygonToPolygon(a, b *Polygon) (bool, error) {
+	if a == nil {
+		return false, fmt.Errorf("the first polygon is nil")
+	}
+
+	if b == nil {
+		return false, fmt.Errorf("the second polygon is nil")
+	}
+
+	return verticesOverlap(a.Vertices(), b.Vertices()), nil
+}
+
+// CollisionPolygonToRectangle detects if there is an intersection
+// between a convex polygon and an oriented rectangle.
+func CollisionPolygonToRectangle(polygon *Polygon, rect *Rectangle) (bool, error) {
+	if polygon == nil {
+		return false, fmt.Errorf("the polygon is nil")
+	}
+
+	if rect == nil {
+		return false, fmt.Errorf("the rectangle is nil")
+	}
+
+	rectVertices := rect.Vertices()
+
+	return verticesOverlap(polygon.Vertices(), rectVertices[:]), nil
+}
+
+// CollisionPolygonToCircle detects if there is an intersection
+// between a convex polygon and a circle.
+func CollisionPolygonToCircle(polygon *Polygon, circle *Circle) (bool, error) {
+	if polygon == nil {
+		return false, fmt.Errorf("the polygon is nil")
+	}
+
+	if circle == nil {
+		return false, fmt.Errorf("the circle is nil")
+	}
+
+	if polygon.ContainsPoint(circle.center) {
+		return true, nil
+	}
+
+	closestPoint := polygon.closestPoint(circle.center)
+
+	return circle.ContainsPoint(closestPoint), nil
+}
+
+// IntersectionLineToPolygon detects if there is an intersection
+// between a line and a convex polygon.
+func IntersectionLineToPolygon(line *Line, polygon *Polygon) (bool, error) {
+	if line == nil {
+		return false, fmt.Errorf("the line is nil")
+	}
+
+	if polygon == nil {
+		return false, fmt.Errorf("the polygon is nil")
+	}
+
+	// The line segment is just a degenerate
+	// polygon with two vertices.
+	return verticesOverlap([]Vector{line.p, line.q},
+		polygon.Vertices()), nil
+}
+
+// CollisionCapsuleToCapsule detects if there is an intersection
+// between two capsules.
+func CollisionCapsuleToCapsule(a, b *Capsule) (bool, error) {
+	if a == nil {
+		return false, fmt.Errorf("the first capsule is nil")
+	}
+
+	if b == nil {
+		return false, fmt.Errorf("the second capsule is nil")
+	}
+
+	distance, _, _ := verticesDistance(a.segment(), b.segment())
+
+	return distance <= a.radius+b.radius, nil
+}
+
+// CollisionCapsuleToCircle detects if there is an intersection
+// between a capsule and a circle.
+func CollisionCapsuleToCircle(capsule *Capsule, circle *Circle) (bool, error) {
+	if capsule == nil {
+		return false, fmt.Errorf("the capsule is nil")
+	}
+
+	if circle == nil {
+		return false, fmt.Errorf("the circle is nil")
+	}
+
+	closestPoint := closestPointOnSegment(capsule.p, capsule.q, circle.center)
+	radiiSum := capsule.radius + circle.radius
+
+	return SquaredDistance(closestPoint, circle.center) <= radiiSum*radiiSum, nil
+}
+
+// IntersectionLineToCapsule detects if there is an intersection
+// between a line and a capsule.
+func IntersectionLineToCapsule(line *Line, capsule *Capsule) (bool, error) {
+	if line == nil {
+		return false, fmt.Errorf("the line is nil")
+	}
+
+	if capsule == nil {
+		return false, fmt.Errorf("the capsule is nil")
+	}
+
+	distance, _, _ := verticesDistance([]Vector{line.p, line.q},
+		capsule.segment())
+
+	return distance <= capsule.radius, nil
+}
+
+// CollisionCapsuleToRectangle detects if there is an intersection
+// between a capsule and an oriented rectangle.
+func CollisionCapsuleToRectangle(capsule *Capsule, rect *Rectangle) (bool, error) {
+	if capsule == nil {
+		return false, fmt.Errorf("the capsule is nil")
+	}
+
+	if rect == nil {
+		return false, fmt.Errorf("the rectangle is nil")
+	}
+
+	vertices := rect.Vertices()
+	distance, _, _ := verticesDistance(capsule.segment(), vertices[:])
+
+	return distance <= capsule.radius, nil
+}
+
+// CollisionCapsuleToPolygon detects if there is an intersection
+// between a capsule and a convex polygon.
+func CollisionCapsuleToPolygon(capsule *Capsule, polygon *Polygon) (bool, error) {
+	if capsule == nil {
+		return false, fmt.Errorf("the capsule is nil")
+	}
+
+	if polygon == nil {
+		return false, fmt.Errorf("the polygon is nil")
+	}
+
+	distance, _, _ := verticesDistance(capsule.segment(), polygon.Vertices())
+
+	return distance <= capsule.radius, nil
+}
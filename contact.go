@@ -16,6 +16,12 @@ func Contact(one, other Shape) ([]Vector, error) {
 		return nil, fmt.Errorf("the second shape is nil")
 	}
 
+	// The user-defined shapes may have the
+	// type names of the built-in ones.
+	if !isBuiltin(one) || !isBuiltin(other) {
+		return registeredContact(one, other)
+	}
+
 	id := one.TypeName() + "_" + other.TypeName()
 
 	switch id {
@@ -45,11 +51,57 @@ func Contact(one, other Shape) ([]Vector, error) {
 
 	case "Rectangle_Line":
 		return ContactLineToRectangle(other.(*Line), one.(*Rectangle))
+
+	case "Polygon_Polygon":
+		return ContactPolygonToPolygon(one.(*Polygon), other.(*Polygon))
+
+	case "Polygon_Rectangle":
+		return ContactPolygonToRectangle(one.(*Polygon), other.(*Rectangle))
+
+	case "Rectangle_Polygon":
+		return ContactPolygonToRectangle(other.(*Polygon), one.(*Rectangle))
+
+	case "Polygon_Circle":
+		return ContactPolygonToCircle(one.(*Polygon), other.(*Circle))
+
+	case "Circle_Polygon":
+		return ContactPolygonToCircle(other.(*Polygon), one.(*Circle))
+
+	case "Line_Polygon":
+		return ContactLineToPolygon(one.(*Line), other.(*Polygon))
+
+	case "Polygon_Line":
+		return ContactLineToPolygon(other.(*Line), one.(*Polygon))
+
+	case "Capsule_Capsule":
+		return ContactCapsuleToCapsule(one.(*Capsule), other.(*Capsule))
+
+	case "Capsule_Circle":
+		return ContactCapsuleToCircle(one.(*Capsule), other.(*Circle))
+
+	case "Circle_Capsule":
+		return ContactCapsuleToCircle(other.(*Capsule), one.(*Circle))
+
+	case "Capsule_Line":
+		return ContactLineToCapsule(other.(*Line), one.(*Capsule))
+
+	case "Line_Capsule":
+		return ContactLineToCapsule(one.(*Line), other.(*Capsule))
+
+	case "Capsule_Rectangle":
+		return ContactCapsuleToRectangle(one.(*Capsule), other.(*Rectangle))
+
+	case "Rectangle_Capsule":
+		return ContactCapsuleToRectangle(other.(*Capsule), one.(*Rectangle))
+
+	case "Capsule_Polygon":
+		return ContactCapsuleToPolygon(one.(*Capsule), other.(*Polygon))
+
+	case "Polygon_Capsule":
+		return ContactCapsuleToPolygon(other.(*Capsule), one.(*Polygon))
 	}
 
-	return nil, fmt.Errorf(
-		"unknown shape type combination: '%s' and '%s'",
-		one.TypeName(), other.TypeName())
+	return registeredContact(one, other)
 }
 
 // ContactLineToCircle returns the contact points between the
@@ -482,3 +534,284 @@ func ContactCircleToCircle(one, other *Circle) ([]Vector, error) {
 
 	return []Vector{pointA, pointB}, nil
 }
+
+// ContactLineToPolygon returns the contacts between the line and
+// the polygon (if they exist).
+func ContactLineToPolygon(line *Line, polygon *Polygon) ([]Vector, error) {
+	if line == nil {
+		return nil, fmt.Errorf("the line is nil")
+	}
+
+	if polygon == nil {
+		return nil, fmt.Errorf("the polygon is nil")
+	}
+
+	sides, err := polygon.sides()
+
+	if err != nil {
+		return nil, err
+	}
+
+	contacts := make([]Vector, 0)
+
+	for _, side := range sides {
+		sideContacts, err := ContactLineToLine(line, side)
+
+		if err != nil {
+			return nil, err
+		}
+
+		contacts = append(contacts, sideContacts...)
+	}
+
+	return contacts, nil
+}
+
+// ContactPolygonToCircle returns the contacts between the polygon and
+// the circle (if they exist).
+func ContactPolygonToCircle(polygon *Polygon, circle *Circle) ([]Vector, error) {
+	if polygon == nil {
+		return nil, fmt.Errorf("the polygon is nil")
+	}
+
+	if circle == nil {
+		return nil, fmt.Errorf("the circle is nil")
+	}
+
+	sides, err := polygon.sides()
+
+	if err != nil {
+		return nil, err
+	}
+
+	contacts := make([]Vector, 0)
+
+	for _, side := range sides {
+		sideContacts, err := ContactLineToCircle(side, circle)
+
+		if err != nil {
+			return nil, err
+		}
+
+		contacts = append(contacts, sideContacts...)
+	}
+
+	return contacts, nil
+}
+
+// ContactPolygonToRectangle returns the contacts between the polygon and
+// the rectangle (if they exist).
+func ContactPolygonToRectangle(polygon *Polygon, rect *Rectangle) ([]Vector, error) {
+	if polygon == nil {
+		return nil, fmt.Errorf("the polygon is nil")
+	}
+
+	if rect == nil {
+		return nil, fmt.Errorf("the rectangle is nil")
+	}
+
+	sides, err := polygon.sides()
+
+	if err != nil {
+		return nil, err
+	}
+
+	contacts := make([]Vector, 0)
+
+	for _, side := range sides {
+		sideContacts, err := ContactLineToRectangle(side, rect)
+
+		if err != nil {
+			return nil, err
+		}
+
+		contacts = append(contacts, sideContacts...)
+	}
+
+	return contacts, nil
+}
+
+// ContactPolygonToPolygon returns the contacts between two polygons
+// (if they exist).
+func ContactPolygonToPolygon(one, other *Polygon) ([]Vector, error) {
+	if one == nil {
+		return nil, fmt.Errorf("the first polygon is nil")
+	}
+
+	if other == nil {
+		return nil, fmt.Errorf("the second polygon is nil")
+	}
+
+	sides, err := one.sides()
+
+	if err != nil {
+		return nil, err
+	}
+
+	contacts := make([]Vector, 0)
+
+	for _, side := range sides {
+		sideContacts, err := ContactLineToPolygon(side, other)
+
+		if err != nil {
+			return nil, err
+		}
+
+		contacts = append(contacts, sideContacts...)
+	}
+
+	return contacts, nil
+}
+
+// contactCapsuleOutline returns the contacts between the capsule
+// outline and some other shape. The contacts of the outline sides
+// and the outline caps are computed with the given functions.
+func contactCapsuleOutline(
+	capsule *Capsule,
+	sideContact func(*Line) ([]Vector, error),
+	capContact func(*Circle) ([]Vector, error),
+) (
+	[]Vector, error,
+) {
+	sides, err := capsule.sides()
+
+	if err != nil {
+		return nil, err
+	}
+
+	caps, err := capsule.caps()
+
+	if err != nil {
+		return nil, err
+	}
+
+	contacts := make([]Vector, 0)
+
+	for _, side := range sides {
+		sideContacts, err := sideContact(side)
+
+		if err != nil {
+			return nil, err
+		}
+
+		contacts = append(contacts, sideContacts...)
+	}
+
+	for _, end := range caps {
+		capContacts, err := capContact(end)
+
+		if err != nil {
+			return nil, err
+		}
+
+		// Only the outer halves of the
+		// cap circles belong to the outline.
+		for _, contact := range capContacts {
+			if capsule.onCap(contact) {
+				contacts = append(contacts, contact)
+			}
+		}
+	}
+
+	return contacts, nil
+}
+
+// ContactLineToCapsule returns the contacts between the line and
+// the capsule (if they exist).
+func ContactLineToCapsule(line *Line, capsule *Capsule) ([]Vector, error) {
+	if line == nil {
+		return nil, fmt.Errorf("the line is nil")
+	}
+
+	if capsule == nil {
+		return nil, fmt.Errorf("the capsule is nil")
+	}
+
+	return contactCapsuleOutline(capsule,
+		func(side *Line) ([]Vector, error) {
+			return ContactLineToLine(line, side)
+		},
+		func(end *Circle) ([]Vector, error) {
+			return ContactLineToCircle(line, end)
+		})
+}
+
+// ContactCapsuleToCircle returns the contacts between the capsule and
+// the circle (if they exist).
+func ContactCapsuleToCircle(capsule *Capsule, circle *Circle) ([]Vector, error) {
+	if capsule == nil {
+		return nil, fmt.Errorf("the capsule is nil")
+	}
+
+	if circle == nil {
+		return nil, fmt.Errorf("the circle is nil")
+	}
+
+	return contactCapsuleOutline(capsule,
+		func(side *Line) ([]Vector, error) {
+			return ContactLineToCircle(side, circle)
+		},
+		func(end *Circle) ([]Vector, error) {
+			return ContactCircleToCircle(end, circle)
+		})
+}
+
+// ContactCapsuleToRectangle returns the contacts between the capsule and
+// the rectangle (if they exist).
+func ContactCapsuleToRectangle(capsule *Capsule, rect *Rectangle) ([]Vector, error) {
+	if capsule == nil {
+		return nil, fmt.Errorf("the capsule is nil")
+	}
+
+	if rect == nil {
+		return nil, fmt.Errorf("the rectangle is nil")
+	}
+
+	return contactCapsuleOutline(capsule,
+		func(side *Line) ([]Vector, error) {
+			return ContactLineToRectangle(side, rect)
+		},
+		func(end *Circle) ([]Vector, error) {
+			return ContactRectangleToCircle(rect, end)
+		})
+}
+
+// ContactCapsuleToPolygon returns the contacts between the capsule and
+// the polygon (if they exist).
+func ContactCapsuleToPolygon(capsule *Capsule, polygon *Polygon) ([]Vector, error) {
+	if capsule == nil {
+		return nil, fmt.Errorf("the capsule is nil")
+	}
+
+	if polygon == nil {
+		return nil, fmt.Errorf("the polygon is nil")
+	}
+
+	return contactCapsuleOutline(capsule,
+		func(side *Line) ([]Vector, error) {
+			return ContactLineToPolygon(side, polygon)
+		},
+		func(end *Circle) ([]Vector, error) {
+			return ContactPolygonToCircle(polygon, end)
+		})
+}
+
+// ContactCapsuleToCapsule returns the contacts between two capsules
+// (if they exist).
+func ContactCapsuleToCapsule(one, other *Capsule) ([]Vector, error) {
+	if one == nil {
+		return nil, fmt.Errorf("the first capsule is nil")
+	}
+
+	if other == nil {
+		return nil, fmt.Errorf("the second capsule is nil")
+	}
+
+	return contactCapsuleOutline(one,
+		func(side *Line) ([]Vector, error) {
+			return ContactLineToCapsule(side, other)
+		},
+		func(end *Circle) ([]Vector, error) {
+			return ContactCapsuleToCircle(other, end)
+		})
+}
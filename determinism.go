@@ -0,0 +1,83 @@
+package cirno
+
+import "sort"
+
+// WithDeterministicOrder makes the space enumerate the pairs of
+// colliding shapes, call the collision handlers and iterate over
+// the shapes across the edges of the wrapped space in the order
+// of the shapes insertion, so the results don't vary between runs.
+//
+// The ties of the raycasts, sweeps and approximations are always
+// broken by the insertion order. The sets of shapes returned
+// by the queries can be ordered with Space.Ordered.
+func WithDeterministicOrder() SpaceOption {
+	return func(space *Space) error {
+		space.deterministic = true
+
+		return nil
+	}
+}
+
+// Deterministic returns true if the space
+// iterates over the shapes in the order
+// of their insertion.
+func (space *Space) Deterministic() bool {
+	return space.deterministic
+}
+
+// Ordered returns the shapes of the set sorted by their IDs
+// in the space, so in the order of their insertion in it.
+// The shapes out of the space are ordered as in
+// Shapes.OrderedItems after the ones within it.
+func (space *Space) Ordered(shapes Shapes) []Shape {
+	items := shapes.Items()
+	sort.Slice(items, func(i, j int) bool {
+		return space.orderedBefore(items[i], items[j])
+	})
+
+	return items
+}
+
+// itemsOf returns the shapes of the set in the order
+// of insertion if the space is deterministic.
+func (space *Space) itemsOf(shapes Shapes) []Shape {
+	if space.deterministic {
+		return space.Ordered(shapes)
+	}
+
+	return shapes.Items()
+}
+
+// orderedBefore returns true if the first shape
+// was inserted in the space before the second one.
+// The shapes out of the space go after the ones
+// within it and are compared by placedBefore.
+func (space *Space) orderedBefore(one, other Shape) bool {
+	oneID, oneInside := space.ids[one]
+	otherID, otherInside := space.ids[other]
+
+	if oneInside != otherInside {
+		return oneInside
+	}
+
+	if oneID != otherID {
+		return oneID < otherID
+	}
+
+	return placedBefore(one, other)
+}
+
+// sortCandidates sorts the pairs of shapes by
+// the insertion order of the first shape and
+// then by the insertion order of the second one.
+func (space *Space) sortCandidates(candidates []candidate) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		one, other := candidates[i], candidates[j]
+
+		if one.shape != other.shape {
+			return space.orderedBefore(one.shape, other.shape)
+		}
+
+		return space.orderedBefore(one.other, other.other)
+	})
+}
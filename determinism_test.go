@@ -0,0 +1,152 @@
+package cirno_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zergon321/cirno"
+)
+
+func TestDeterministicOrder(t *testing.T) {
+	run := func() ([][2]int, []int) {
+		space, err := cirno.NewSpace(2, 2, 40, 40, cirno.NewVector(-20, -20),
+			cirno.NewVector(20, 20), false, cirno.WithDeterministicOrder())
+		assert.Nil(t, err)
+		assert.True(t, space.Deterministic())
+
+		shapes := []cirno.Shape{}
+		numbers := map[cirno.Shape]int{}
+
+		for i := 0; i < 20; i++ {
+			circle, err := cirno.NewCircle(
+				cirno.NewVector(float64(i%5), float64(i/5)), 1)
+			assert.Nil(t, err)
+			shapes = append(shapes, circle)
+			numbers[circle] = i
+		}
+
+		err = space.Add(shapes...)
+		assert.Nil(t, err)
+
+		pairs, err := space.CollisionPairs()
+		assert.Nil(t, err)
+
+		order := [][2]int{}
+
+		for _, pair := range pairs {
+			order = append(order, [2]int{numbers[pair.One], numbers[pair.Other]})
+		}
+
+		hits, err := space.RaycastAll(cirno.NewVector(-10, 0),
+			cirno.Right(), 30, 0, false)
+		assert.Nil(t, err)
+
+		hitOrder := []int{}
+
+		for _, hit := range hits {
+			hitOrder = append(hitOrder, numbers[hit.Shape])
+		}
+
+		return order, hitOrder
+	}
+
+	pairs, hits := run()
+	assert.NotEmpty(t, pairs)
+
+	// The pairs are sorted by the insertion order.
+	for i, pair := range pairs {
+		assert.Less(t, pair[0], pair[1])
+
+		if i > 0 {
+			previous := pairs[i-1]
+			assert.True(t, previous[0] < pair[0] ||
+				previous[0] == pair[0] && previous[1] < pair[1])
+		}
+	}
+
+	for i := 0; i < 10; i++ {
+		otherPairs, otherHits := run()
+		assert.Equal(t, pairs, otherPairs)
+		assert.Equal(t, hits, otherHits)
+	}
+}
+
+func TestOrderedItems(t *testing.T) {
+	space, err := cirno.NewSpace(2, 2, 40, 40, cirno.NewVector(-20, -20),
+		cirno.NewVector(20, 20), false)
+	assert.Nil(t, err)
+
+	shapes := []cirno.Shape{}
+
+	for i := 0; i < 10; i++ {
+		circle, err := cirno.NewCircle(cirno.NewVector(0, 0), 1)
+		assert.Nil(t, err)
+		shapes = append(shapes, circle)
+	}
+
+	err = space.Add(shapes...)
+	assert.Nil(t, err)
+	assert.Equal(t, shapes, space.Shapes().OrderedItems())
+
+	// The tie is broken by the insertion order.
+	hit, _, err := space.Raycast(cirno.NewVector(-10, 0), cirno.Right(), 20, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, shapes[0], hit)
+}
+
+func TestOrderedItemsOutOfSpace(t *testing.T) {
+	mover, err := cirno.NewCircle(cirno.NewVector(0, 0), 1)
+	assert.Nil(t, err)
+	upper, err := cirno.NewCircle(cirno.NewVector(5, 1), 1)
+	assert.Nil(t, err)
+	lower, err := cirno.NewCircle(cirno.NewVector(5, -1), 1)
+	assert.Nil(t, err)
+	obstacles := cirno.Shapes{upper: {}, lower: {}}
+
+	// The shapes never added to a space are
+	// ordered by their types and positions.
+	for i := 0; i < 20; i++ {
+		assert.Equal(t, []cirno.Shape{lower, upper}, obstacles.OrderedItems())
+
+		_, _, shape, err := cirno.Approximate(mover, cirno.NewVector(10, 0),
+			0, obstacles, 10, false)
+		assert.Nil(t, err)
+		assert.Equal(t, lower, shape)
+	}
+}
+
+func TestSpaceOrdered(t *testing.T) {
+	one, err := cirno.NewSpace(2, 2, 40, 40, cirno.NewVector(-20, -20),
+		cirno.NewVector(20, 20), false)
+	assert.Nil(t, err)
+	other, err := cirno.NewSpace(2, 2, 40, 40, cirno.NewVector(-20, -20),
+		cirno.NewVector(20, 20), false)
+	assert.Nil(t, err)
+
+	first, err := cirno.NewCircle(cirno.NewVector(3, 0), 1)
+	assert.Nil(t, err)
+	second, err := cirno.NewCircle(cirno.NewVector(-3, 0), 1)
+	assert.Nil(t, err)
+	small, err := cirno.NewCircle(cirno.NewVector(0, 0), 1)
+	assert.Nil(t, err)
+	large, err := cirno.NewCircle(cirno.NewVector(0, 0), 2)
+	assert.Nil(t, err)
+
+	err = one.Add(first, second)
+	assert.Nil(t, err)
+	err = other.Add(second, first)
+	assert.Nil(t, err)
+
+	// The shapes are ordered by the IDs in the given space,
+	// the ones out of it go after them and are ordered by
+	// their geometry if they have the same position.
+	shapes := cirno.Shapes{first: {}, second: {}, small: {}, large: {}}
+
+	for i := 0; i < 20; i++ {
+		assert.Equal(t, []cirno.Shape{first, second, large, small},
+			one.Ordered(shapes))
+		assert.Equal(t, []cirno.Shape{second, first, large, small},
+			other.Ordered(shapes))
+	}
+}
@@ -2,9 +2,28 @@ package cirno
 
 // domain contains all the
 // quad tree nodes the shape
-// belongs to.
+// belongs to and its ID
+// in the space.
 type domain struct {
 	treeNodes []*quadTreeNode
+	id        uint64
+}
+
+// ID returns the unique ID assigned to the shape
+// when it was added to the space. The IDs increase
+// in the order of insertion. It's 0 if the shape
+// has never been added to a space.
+//
+// The shape added to several spaces has the ID assigned
+// by the last of them, Space.IDOf should be used
+// to get its ID in the other spaces.
+func (d *domain) ID() uint64 {
+	return d.id
+}
+
+// setID assigns the ID to the shape.
+func (d *domain) setID(id uint64) {
+	d.id = id
 }
 
 // nodes returns all the quad tree nodes the shape
@@ -16,6 +35,20 @@ func (d *domain) nodes() []*quadTreeNode {
 	return nodes
 }
 
+// sharesNodeBefore returns true if both the shape and the
+// other one belong to a node of the same quad tree which
+// precedes the given node.
+func (d *domain) sharesNodeBefore(node *quadTreeNode, other Shape) bool {
+	for _, treeNode := range d.treeNodes {
+		if treeNode.tree == node.tree && treeNode.precedes(node) &&
+			other.containsNode(treeNode) {
+			return true
+		}
+	}
+
+	return false
+}
+
 // addNodes adds new nodes in the list of the nodes
 // the shape belongs to.
 func (d *domain) addNodes(nodes ...*quadTreeNode) {
@@ -0,0 +1,671 @@
+package cirno
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// spaceFormatVersion is the version of the
+// encoding of the space. It's increased on every
+// incompatible change of the encoding.
+const spaceFormatVersion = 1
+
+// spaceMagic is the beginning of
+// the binary encoding of the space.
+var spaceMagic = [4]byte{'C', 'R', 'N', 'O'}
+
+// shapeTypes are the codes of the built-in
+// shape types in the binary encoding.
+var shapeTypes = map[string]byte{
+	"Circle":    1,
+	"Rectangle": 2,
+	"Line":      3,
+	"Polygon":   4,
+	"Capsule":   5,
+}
+
+// shapeRecord is the encoded shape. Only
+// the fields of the shape type are set.
+type shapeRecord struct {
+	Type     string   `json:"type"`
+	Center   *Vector  `json:"center,omitempty"`
+	P        *Vector  `json:"p,omitempty"`
+	Q        *Vector  `json:"q,omitempty"`
+	Radius   float64  `json:"radius,omitempty"`
+	Width    float64  `json:"width,omitempty"`
+	Height   float64  `json:"height,omitempty"`
+	Vertices []Vector `json:"vertices,omitempty"`
+	Angle    float64  `json:"angle"`
+	Identity int32    `json:"identity"`
+	Mask     int32    `json:"mask"`
+	Sensor   bool     `json:"sensor,omitempty"`
+}
+
+// spaceShapeRecord is the encoded
+// shape of the space.
+type spaceShapeRecord struct {
+	ID   uint64   `json:"id"`
+	Kind BodyKind `json:"kind"`
+	shapeRecord
+}
+
+// spaceRecord is the encoded space.
+type spaceRecord struct {
+	Version           int                `json:"version"`
+	Min               Vector             `json:"min"`
+	Max               Vector             `json:"max"`
+	Width             float64            `json:"width"`
+	Height            float64            `json:"height"`
+	SubdivisionFactor int                `json:"subdivisionFactor"`
+	ShapesInArea      int                `json:"shapesInArea"`
+	UseTags           bool               `json:"useTags"`
+	BoundsPolicy      BoundsPolicy       `json:"boundsPolicy"`
+	Deterministic     bool               `json:"deterministic,omitempty"`
+	SleepSteps        int                `json:"sleepSteps,omitempty"`
+	NextID            uint64             `json:"nextID"`
+	Shapes            []spaceShapeRecord `json:"shapes"`
+}
+
+// recordOf returns the record of the built-in shape.
+func recordOf(shape Shape) (shapeRecord, error) {
+	record := shapeRecord{
+		Type:     shape.TypeName(),
+		Angle:    shape.Angle(),
+		Identity: shape.GetIdentity(),
+		Mask:     shape.GetMask(),
+		Sensor:   shape.IsSensor(),
+	}
+
+	switch original := shape.(type) {
+	case *Circle:
+		center := original.center
+		record.Center = &center
+		record.Radius = original.radius
+
+	case *Rectangle:
+		center := original.center
+		record.Center = &center
+		record.Width = original.Width()
+		record.Height = original.Height()
+
+	case *Line:
+		p, q := original.p, original.q
+		record.P = &p
+		record.Q = &q
+
+	case *Polygon:
+		center := original.center
+		record.Center = &center
+		record.Vertices = original.LocalVertices()
+
+	case *Capsule:
+		p, q := original.p, original.q
+		record.P = &p
+		record.Q = &q
+		record.Radius = original.radius
+
+	default:
+		return record, fmt.Errorf(
+			"the shape of type '%s' cannot be encoded", shape.TypeName())
+	}
+
+	return record, nil
+}
+
+// shape creates the shape from the record.
+func (record shapeRecord) shape() (Shape, error) {
+	var shape Shape
+
+	switch record.Type {
+	case "Circle":
+		if record.Center == nil {
+			return nil, fmt.Errorf("the circle has no center")
+		}
+
+		circle, err := NewCircle(*record.Center, record.Radius)
+
+		if err != nil {
+			return nil, err
+		}
+
+		shape = circle
+
+	case "Rectangle":
+		if record.Center == nil {
+			return nil, fmt.Errorf("the rectangle has no center")
+		}
+
+		rect, err := NewRectangle(*record.Center,
+			record.Width, record.Height, record.Angle)
+
+		if err != nil {
+			return nil, err
+		}
+
+		shape = rect
+
+	case "Line":
+		if record.P == nil || record.Q == nil {
+			return nil, fmt.Errorf("the line has no end points")
+		}
+
+		line, err := NewLine(*record.P, *record.Q)
+
+		if err != nil {
+			return nil, err
+		}
+
+		line.angle = record.Angle
+		shape = line
+
+	case "Polygon":
+		if record.Center == nil {
+			return nil, fmt.Errorf("the polygon has no center")
+		}
+
+		polygon, err := NewPolygon(*record.Center,
+			record.Vertices, record.Angle)
+
+		if err != nil {
+			return nil, err
+		}
+
+		shape = polygon
+
+	case "Capsule":
+		if record.P == nil || record.Q == nil {
+			return nil, fmt.Errorf("the capsule has no end points")
+		}
+
+		capsule, err := NewCapsule(*record.P, *record.Q, record.Radius)
+
+		if err != nil {
+			return nil, err
+		}
+
+		capsule.angle = record.Angle
+		shape = capsule
+
+	default:
+		return nil, fmt.Errorf("unknown shape type: '%s'", record.Type)
+	}
+
+	shape.SetIdentity(record.Identity)
+	shape.SetMask(record.Mask)
+	shape.SetSensor(record.Sensor)
+
+	return shape, nil
+}
+
+// marshalShapeJSON encodes the built-in shape to JSON.
+func marshalShapeJSON(shape Shape) ([]byte, error) {
+	record, err := recordOf(shape)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(record)
+}
+
+// unmarshalShapeJSON decodes the shape from JSON
+// and copies its geometry and tags to the given shape.
+// The data and the domain of the shape are kept.
+func unmarshalShapeJSON(shape Shape, data []byte) error {
+	var record shapeRecord
+
+	if err := json.Unmarshal(data, &record); err != nil {
+		return err
+	}
+
+	if record.Type != shape.TypeName() {
+		return fmt.Errorf("expected the shape of type '%s', but got '%s'",
+			shape.TypeName(), record.Type)
+	}
+
+	decoded, err := record.shape()
+
+	if err != nil {
+		return err
+	}
+
+	restoreGeometry(shape, decoded)
+	shape.SetIdentity(decoded.GetIdentity())
+	shape.SetMask(decoded.GetMask())
+	shape.SetSensor(decoded.IsSensor())
+
+	return nil
+}
+
+// UnmarshalShapeJSON decodes the built-in shape
+// of any type from JSON.
+func UnmarshalShapeJSON(data []byte) (Shape, error) {
+	var record shapeRecord
+
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, err
+	}
+
+	return record.shape()
+}
+
+// MarshalJSON encodes the circle to JSON.
+func (c *Circle) MarshalJSON() ([]byte, error) {
+	return marshalShapeJSON(c)
+}
+
+// UnmarshalJSON decodes the circle from JSON.
+func (c *Circle) UnmarshalJSON(data []byte) error {
+	return unmarshalShapeJSON(c, data)
+}
+
+// MarshalJSON encodes the rectangle to JSON.
+func (r *Rectangle) MarshalJSON() ([]byte, error) {
+	return marshalShapeJSON(r)
+}
+
+// UnmarshalJSON decodes the rectangle from JSON.
+func (r *Rectangle) UnmarshalJSON(data []byte) error {
+	return unmarshalShapeJSON(r, data)
+}
+
+// MarshalJSON encodes the line to JSON.
+func (l *Line) MarshalJSON() ([]byte, error) {
+	return marshalShapeJSON(l)
+}
+
+// UnmarshalJSON decodes the line from JSON.
+func (l *Line) UnmarshalJSON(data []byte) error {
+	return unmarshalShapeJSON(l, data)
+}
+
+// MarshalJSON encodes the polygon to JSON.
+func (p *Polygon) MarshalJSON() ([]byte, error) {
+	return marshalShapeJSON(p)
+}
+
+// UnmarshalJSON decodes the polygon from JSON.
+func (p *Polygon) UnmarshalJSON(data []byte) error {
+	return unmarshalShapeJSON(p, data)
+}
+
+// MarshalJSON encodes the capsule to JSON.
+func (c *Capsule) MarshalJSON() ([]byte, error) {
+	return marshalShapeJSON(c)
+}
+
+// UnmarshalJSON decodes the capsule from JSON.
+func (c *Capsule) UnmarshalJSON(data []byte) error {
+	return unmarshalShapeJSON(c, data)
+}
+
+// record returns the record of the space
+// with the shapes sorted by their IDs.
+func (space *Space) record() (spaceRecord, error) {
+	record := spaceRecord{
+		Version:           spaceFormatVersion,
+		Min:               space.min,
+		Max:               space.max,
+		Width:             space.width,
+		Height:            space.height,
+		SubdivisionFactor: space.subdivisionFactor,
+		ShapesInArea:      space.shapesInArea,
+		UseTags:           space.useTags,
+		BoundsPolicy:      space.boundsPolicy,
+		Deterministic:     space.deterministic,
+		SleepSteps:        space.sleepSteps,
+		NextID:            space.nextID,
+		Shapes:            make([]spaceShapeRecord, 0, len(space.shapes)),
+	}
+
+	for _, shape := range space.Ordered(space.shapes) {
+		shapeRecord, err := recordOf(shape)
+
+		if err != nil {
+			return record, err
+		}
+
+		record.Shapes = append(record.Shapes, spaceShapeRecord{
+			ID:          space.ids[shape],
+			Kind:        space.kinds[shape],
+			shapeRecord: shapeRecord,
+		})
+	}
+
+	return record, nil
+}
+
+// space creates the space from the record. The shapes
+// are added in the order of their IDs and keep them.
+func (record spaceRecord) space() (*Space, error) {
+	if record.Version != spaceFormatVersion {
+		return nil, fmt.Errorf(
+			"unsupported space format version: %d", record.Version)
+	}
+
+	options := []SpaceOption{WithBoundsPolicy(record.BoundsPolicy)}
+
+	if record.Deterministic {
+		options = append(options, WithDeterministicOrder())
+	}
+
+	if record.SleepSteps > 0 {
+		options = append(options, WithSleeping(record.SleepSteps))
+	}
+
+	space, err := NewSpace(record.SubdivisionFactor, record.ShapesInArea,
+		record.Width, record.Height, record.Min, record.Max,
+		record.UseTags, options...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	shapes := make([]spaceShapeRecord, len(record.Shapes))
+	copy(shapes, record.Shapes)
+	sort.SliceStable(shapes, func(i, j int) bool {
+		return shapes[i].ID < shapes[j].ID
+	})
+
+	for _, shapeRecord := range shapes {
+		if _, ok := space.byID[shapeRecord.ID]; ok || shapeRecord.ID == 0 {
+			return nil, fmt.Errorf("invalid shape ID: %d", shapeRecord.ID)
+		}
+
+		shape, err := shapeRecord.shape()
+
+		if err != nil {
+			return nil, err
+		}
+
+		if err := space.AddAs(shapeRecord.Kind, shape); err != nil {
+			return nil, err
+		}
+
+		// Keep the encoded ID instead
+		// of the assigned one.
+		delete(space.byID, space.ids[shape])
+		shape.setID(shapeRecord.ID)
+		space.ids[shape] = shapeRecord.ID
+		space.byID[shapeRecord.ID] = shape
+
+		if shapeRecord.ID > space.nextID {
+			space.nextID = shapeRecord.ID
+		}
+	}
+
+	if record.NextID > space.nextID {
+		space.nextID = record.NextID
+	}
+
+	return space, nil
+}
+
+// MarshalJSON encodes the space with its parameters
+// and all its shapes to JSON. Only the built-in shapes
+// can be encoded. The broadphase is not encoded,
+// the decoded space uses the quad tree.
+func (space *Space) MarshalJSON() ([]byte, error) {
+	record, err := space.record()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(record)
+}
+
+// UnmarshalJSON replaces the space with
+// the one decoded from JSON.
+func (space *Space) UnmarshalJSON(data []byte) error {
+	var record spaceRecord
+
+	if err := json.Unmarshal(data, &record); err != nil {
+		return err
+	}
+
+	decoded, err := record.space()
+
+	if err != nil {
+		return err
+	}
+
+	*space = *decoded
+
+	return nil
+}
+
+// MarshalBinary encodes the space the same way as
+// MarshalJSON does, but to the compact binary format.
+// The format starts with the magic bytes and the version.
+func (space *Space) MarshalBinary() ([]byte, error) {
+	record, err := space.record()
+
+	if err != nil {
+		return nil, err
+	}
+
+	buffer := &bytes.Buffer{}
+	var flags uint8
+
+	if record.UseTags {
+		flags |= 1
+	}
+
+	if record.Deterministic {
+		flags |= 2
+	}
+
+	values := []interface{}{
+		spaceMagic,
+		uint16(record.Version),
+		record.Min, record.Max,
+		record.Width, record.Height,
+		int32(record.SubdivisionFactor),
+		int32(record.ShapesInArea),
+		flags,
+		uint8(record.BoundsPolicy),
+		int32(record.SleepSteps),
+		record.NextID,
+		uint32(len(record.Shapes)),
+	}
+
+	for _, shapeRecord := range record.Shapes {
+		var sensor uint8
+
+		if shapeRecord.Sensor {
+			sensor = 1
+		}
+
+		values = append(values,
+			shapeRecord.ID,
+			uint8(shapeRecord.Kind),
+			shapeTypes[shapeRecord.Type],
+			shapeRecord.Identity,
+			shapeRecord.Mask,
+			sensor,
+			shapeRecord.Angle)
+
+		switch shapeRecord.Type {
+		case "Circle":
+			values = append(values, *shapeRecord.Center,
+				shapeRecord.Radius)
+
+		case "Rectangle":
+			values = append(values, *shapeRecord.Center,
+				shapeRecord.Width, shapeRecord.Height)
+
+		case "Line":
+			values = append(values, *shapeRecord.P, *shapeRecord.Q)
+
+		case "Polygon":
+			values = append(values, *shapeRecord.Center,
+				uint32(len(shapeRecord.Vertices)), shapeRecord.Vertices)
+
+		case "Capsule":
+			values = append(values, *shapeRecord.P,
+				*shapeRecord.Q, shapeRecord.Radius)
+		}
+	}
+
+	for _, value := range values {
+		if err := binary.Write(buffer, binary.LittleEndian, value); err != nil {
+			return nil, err
+		}
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// UnmarshalBinary replaces the space with the one
+// decoded from the binary format.
+func (space *Space) UnmarshalBinary(data []byte) error {
+	reader := bytes.NewReader(data)
+	read := func(values ...interface{}) error {
+		for _, value := range values {
+			if err := binary.Read(reader, binary.LittleEndian, value); err != nil {
+				return fmt.Errorf("couldn't decode the space: %w", err)
+			}
+		}
+
+		return nil
+	}
+
+	var (
+		magic             [4]byte
+		version           uint16
+		subdivisionFactor int32
+		shapesInArea      int32
+		flags             uint8
+		boundsPolicy      uint8
+		sleepSteps        int32
+		count             uint32
+		record            spaceRecord
+	)
+
+	if err := read(&magic); err != nil {
+		return err
+	}
+
+	if magic != spaceMagic {
+		return fmt.Errorf("the data is not an encoded space")
+	}
+
+	if err := read(&version); err != nil {
+		return err
+	}
+
+	if version != spaceFormatVersion {
+		return fmt.Errorf(
+			"unsupported space format version: %d", version)
+	}
+
+	err := read(&record.Min, &record.Max, &record.Width, &record.Height,
+		&subdivisionFactor, &shapesInArea, &flags, &boundsPolicy,
+		&sleepSteps, &record.NextID, &count)
+
+	if err != nil {
+		return err
+	}
+
+	record.Version = int(version)
+	record.SubdivisionFactor = int(subdivisionFactor)
+	record.ShapesInArea = int(shapesInArea)
+	record.UseTags = flags&1 != 0
+	record.Deterministic = flags&2 != 0
+	record.BoundsPolicy = BoundsPolicy(boundsPolicy)
+	record.SleepSteps = int(sleepSteps)
+
+	// Every shape takes at least 30 bytes,
+	// so the count can't be bigger.
+	if int(count) > reader.Len()/30 {
+		return fmt.Errorf("the number of the shapes is invalid: %d", count)
+	}
+
+	record.Shapes = make([]spaceShapeRecord, 0, count)
+
+	for i := uint32(0); i < count; i++ {
+		var (
+			shapeRecord spaceShapeRecord
+			kind        uint8
+			shapeType   uint8
+			sensor      uint8
+		)
+
+		err := read(&shapeRecord.ID, &kind, &shapeType,
+			&shapeRecord.Identity, &shapeRecord.Mask,
+			&sensor, &shapeRecord.Angle)
+
+		if err != nil {
+			return err
+		}
+
+		shapeRecord.Kind = BodyKind(kind)
+		shapeRecord.Sensor = sensor != 0
+
+		for name, code := range shapeTypes {
+			if code == shapeType {
+				shapeRecord.Type = name
+			}
+		}
+
+		var center, p, q Vector
+
+		switch shapeRecord.Type {
+		case "Circle":
+			err = read(&center, &shapeRecord.Radius)
+			shapeRecord.Center = &center
+
+		case "Rectangle":
+			err = read(&center, &shapeRecord.Width, &shapeRecord.Height)
+			shapeRecord.Center = &center
+
+		case "Line":
+			err = read(&p, &q)
+			shapeRecord.P, shapeRecord.Q = &p, &q
+
+		case "Polygon":
+			var vertexCount uint32
+
+			if err := read(&center, &vertexCount); err != nil {
+				return err
+			}
+
+			// Every vertex takes 16 bytes.
+			if int(vertexCount) > reader.Len()/16 {
+				return fmt.Errorf(
+					"the number of the vertices is invalid: %d", vertexCount)
+			}
+
+			shapeRecord.Center = &center
+			shapeRecord.Vertices = make([]Vector, vertexCount)
+			err = read(shapeRecord.Vertices)
+
+		case "Capsule":
+			err = read(&p, &q, &shapeRecord.Radius)
+			shapeRecord.P, shapeRecord.Q = &p, &q
+
+		default:
+			return fmt.Errorf("unknown shape type code: %d", shapeType)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		record.Shapes = append(record.Shapes, shapeRecord)
+	}
+
+	decoded, err := record.space()
+
+	if err != nil {
+		return err
+	}
+
+	*space = *decoded
+
+	return nil
+}
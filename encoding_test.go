@@ -0,0 +1,132 @@
+package cirno_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zergon321/cirno"
+)
+
+func TestShapeJSON(t *testing.T) {
+	rect, err := cirno.NewRectangle(cirno.NewVector(1, 2), 4, 2, 30)
+	assert.Nil(t, err)
+	rect.SetIdentity(3)
+	rect.SetMask(5)
+	rect.SetSensor(true)
+
+	data, err := json.Marshal(rect)
+	assert.Nil(t, err)
+
+	decoded := &cirno.Rectangle{}
+	err = json.Unmarshal(data, decoded)
+	assert.Nil(t, err)
+	assert.Equal(t, rect.Center(), decoded.Center())
+	assert.Equal(t, rect.Angle(), decoded.Angle())
+	assert.Equal(t, rect.Width(), decoded.Width())
+	assert.Equal(t, rect.Height(), decoded.Height())
+	assert.Equal(t, int32(3), decoded.GetIdentity())
+	assert.Equal(t, int32(5), decoded.GetMask())
+	assert.True(t, decoded.IsSensor())
+
+	// The type must match.
+	err = json.Unmarshal(data, &cirno.Circle{})
+	assert.NotNil(t, err)
+
+	shape, err := cirno.UnmarshalShapeJSON(data)
+	assert.Nil(t, err)
+	assert.Equal(t, "Rectangle", shape.TypeName())
+
+	_, err = cirno.UnmarshalShapeJSON([]byte(`{"type":"Star"}`))
+	assert.NotNil(t, err)
+}
+
+func TestSpaceEncoding(t *testing.T) {
+	space, err := cirno.NewSpace(4, 3, 60, 60, cirno.NewVector(-30, -30),
+		cirno.NewVector(30, 30), true, cirno.WithBoundsPolicy(cirno.BoundsClamp))
+	assert.Nil(t, err)
+
+	circle, err := cirno.NewCircle(cirno.NewVector(1, 1), 2)
+	assert.Nil(t, err)
+	circle.SetIdentity(1)
+	circle.SetMask(2)
+	rect, err := cirno.NewRectangle(cirno.NewVector(-5, 3), 3, 1, 45)
+	assert.Nil(t, err)
+	line, err := cirno.NewLine(cirno.NewVector(0, -10), cirno.NewVector(10, -12))
+	assert.Nil(t, err)
+	polygon, err := cirno.NewPolygon(cirno.NewVector(10, 10), []cirno.Vector{
+		cirno.NewVector(-1, -1), cirno.NewVector(1, -1), cirno.NewVector(0, 2),
+	}, 15)
+	assert.Nil(t, err)
+	capsule, err := cirno.NewCapsule(cirno.NewVector(-10, -10),
+		cirno.NewVector(-8, -6), 0.5)
+	assert.Nil(t, err)
+
+	err = space.Add(circle, rect, line, polygon)
+	assert.Nil(t, err)
+	err = space.AddAs(cirno.BodyStatic, capsule)
+	assert.Nil(t, err)
+	err = space.Remove(rect)
+	assert.Nil(t, err)
+
+	check := func(decoded *cirno.Space) {
+		assert.Equal(t, space.Min(), decoded.Min())
+		assert.Equal(t, space.Max(), decoded.Max())
+		assert.True(t, decoded.UseTags())
+		assert.Equal(t, cirno.BoundsClamp, decoded.BoundsPolicy())
+		assert.Len(t, decoded.Shapes(), 4)
+
+		for _, original := range space.Shapes().OrderedItems() {
+			shape, err := decoded.ByID(original.ID())
+			assert.Nil(t, err)
+			assert.Equal(t, original.TypeName(), shape.TypeName())
+			assert.True(t, original.Center().ApproximatelyEqual(shape.Center()))
+			assert.Equal(t, original.Angle(), shape.Angle())
+			assert.Equal(t, original.GetIdentity(), shape.GetIdentity())
+			assert.Equal(t, original.GetMask(), shape.GetMask())
+
+			originalMin, originalMax := original.GetBoundingBox()
+			min, max := shape.GetBoundingBox()
+			assert.True(t, originalMin.ApproximatelyEqual(min))
+			assert.True(t, originalMax.ApproximatelyEqual(max))
+		}
+
+		shape, err := decoded.ByID(capsule.ID())
+		assert.Nil(t, err)
+		kind, err := decoded.KindOf(shape)
+		assert.Nil(t, err)
+		assert.Equal(t, cirno.BodyStatic, kind)
+
+		// The next shape gets the next ID.
+		other, err := cirno.NewCircle(cirno.NewVector(0, 0), 1)
+		assert.Nil(t, err)
+		err = decoded.Add(other)
+		assert.Nil(t, err)
+		assert.Equal(t, uint64(6), other.ID())
+	}
+
+	data, err := json.Marshal(space)
+	assert.Nil(t, err)
+	decoded := &cirno.Space{}
+	err = json.Unmarshal(data, decoded)
+	assert.Nil(t, err)
+	check(decoded)
+
+	data, err = space.MarshalBinary()
+	assert.Nil(t, err)
+	decoded = &cirno.Space{}
+	err = decoded.UnmarshalBinary(data)
+	assert.Nil(t, err)
+	check(decoded)
+
+	err = decoded.UnmarshalBinary(data[:len(data)-3])
+	assert.NotNil(t, err)
+	err = decoded.UnmarshalBinary([]byte("JUNK"))
+	assert.NotNil(t, err)
+
+	// The version is checked.
+	data[4] = 2
+	err = decoded.UnmarshalBinary(data)
+	assert.NotNil(t, err)
+}
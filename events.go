@@ -0,0 +1,254 @@
+package cirno
+
+import (
+	"fmt"
+	"sort"
+)
+
+// CollisionHandler contains the callbacks called by Space.Step
+// on the collision transitions of the shape. The first argument
+// of the callback is the handled shape, the second one is
+// the shape it collides with. Any callback may be nil.
+type CollisionHandler struct {
+	// Begin is called when the shapes start colliding.
+	Begin func(shape, other Shape)
+	// Persist is called every step
+	// the shapes keep colliding.
+	Persist func(shape, other Shape)
+	// End is called when the shapes stop colliding
+	// or one of them is removed from the space.
+	End func(shape, other Shape)
+}
+
+// collisionEvent is the collision transition
+// of the pair of shapes.
+type collisionEvent int
+
+const (
+	collisionBegin collisionEvent = iota
+	collisionPersist
+	collisionEnd
+)
+
+// collisionTransition is the pending
+// event for the pair of shapes.
+type collisionTransition struct {
+	event collisionEvent
+	shape Shape
+	other Shape
+}
+
+// HandleShape sets the collision handler for the shape.
+// The handler is dropped when the shape is removed from
+// the space, after the End callbacks for its contacts
+// are called by the next step.
+func (space *Space) HandleShape(shape Shape, handler CollisionHandler) error {
+	if shape == nil {
+		return fmt.Errorf("the shape is nil")
+	}
+
+	space.ownHandlers()
+	space.shapeHandlers[shape] = handler
+
+	return nil
+}
+
+// RemoveShapeHandler removes the collision handler of the shape.
+func (space *Space) RemoveShapeHandler(shape Shape) error {
+	if shape == nil {
+		return fmt.Errorf("the shape is nil")
+	}
+
+	space.ownHandlers()
+	delete(space.shapeHandlers, shape)
+	delete(space.removedHandlers, shape)
+
+	return nil
+}
+
+// dropHandler removes the collision handler of the shape
+// removed from the space. The handler is kept until the
+// next step to call the End callbacks.
+func (space *Space) dropHandler(shape Shape) {
+	handler, ok := space.shapeHandlers[shape]
+
+	if !ok {
+		return
+	}
+
+	space.ownHandlers()
+	space.removedHandlers[shape] = handler
+	delete(space.shapeHandlers, shape)
+}
+
+// ownHandlers copies the shape handlers if they're
+// shared with a snapshot, so they can be changed.
+func (space *Space) ownHandlers() {
+	if !space.handlersShared {
+		return
+	}
+
+	handlers := make(map[Shape]CollisionHandler, len(space.shapeHandlers))
+
+	for shape, handler := range space.shapeHandlers {
+		handlers[shape] = handler
+	}
+
+	space.shapeHandlers = handlers
+	space.handlersShared = false
+}
+
+// HandleIdentity sets the collision handler for all the
+// shapes matching the specified identity template.
+func (space *Space) HandleIdentity(identity int32, handler CollisionHandler) {
+	space.identityHandlers[identity] = handler
+}
+
+// RemoveIdentityHandler removes the collision handler
+// of the specified identity template.
+func (space *Space) RemoveIdentityHandler(identity int32) {
+	delete(space.identityHandlers, identity)
+}
+
+// Step finds all the colliding shapes, compares them to the ones
+// found on the previous step and calls the collision handlers
+// for the pairs of shapes that started colliding, kept
+// colliding or stopped colliding.
+//
+// Returns the same dictionary as CollidingShapes does. The contacts
+// between the sleeping shapes are kept from the previous step.
+func (space *Space) Step() (map[Shape]Shapes, error) {
+	collidingShapes, err := space.CollidingShapes()
+
+	if err != nil {
+		return nil, err
+	}
+
+	space.keepSleepingContacts(collidingShapes)
+
+	transitions := []collisionTransition{}
+
+	for shape, others := range collidingShapes {
+		previous := space.contacts[shape]
+
+		for other := range others {
+			event := collisionBegin
+
+			if _, ok := previous[other]; ok {
+				event = collisionPersist
+			}
+
+			transitions = append(transitions, collisionTransition{
+				event: event,
+				shape: shape,
+				other: other,
+			})
+		}
+	}
+
+	for shape, others := range space.contacts {
+		current := collidingShapes[shape]
+
+		for other := range others {
+			if _, ok := current[other]; ok {
+				continue
+			}
+
+			transitions = append(transitions, collisionTransition{
+				event: collisionEnd,
+				shape: shape,
+				other: other,
+			})
+		}
+	}
+
+	if space.deterministic {
+		sort.SliceStable(transitions, func(i, j int) bool {
+			one, other := transitions[i], transitions[j]
+
+			if one.shape != other.shape {
+				return space.orderedBefore(one.shape, other.shape)
+			}
+
+			if one.other != other.other {
+				return space.orderedBefore(one.other, other.other)
+			}
+
+			return one.event < other.event
+		})
+	}
+
+	space.contacts = make(map[Shape]Shapes, len(collidingShapes))
+
+	for shape, others := range collidingShapes {
+		space.contacts[shape] = others.Copy()
+	}
+
+	// The handlers of the shapes removed since the previous
+	// step are called for the last time. The shapes removed
+	// by the handlers keep theirs until the next step.
+	removed := space.removedHandlers
+	space.removedHandlers = map[Shape]CollisionHandler{}
+
+	// The handlers are called after all the transitions
+	// are found, so they can safely modify the space.
+	for _, transition := range transitions {
+		space.handleTransition(transition, removed)
+	}
+
+	space.advanceSleep()
+
+	return collidingShapes, nil
+}
+
+// handleTransition calls all the handlers matching the shape
+// of the transition. The handler of the removed shape is
+// called only for the end of its collisions.
+func (space *Space) handleTransition(transition collisionTransition, removed map[Shape]CollisionHandler) {
+	if handler, ok := space.shapeHandlers[transition.shape]; ok {
+		handler.call(transition)
+	} else if handler, ok := removed[transition.shape]; ok &&
+		transition.event == collisionEnd {
+		handler.call(transition)
+	}
+
+	identity := transition.shape.GetIdentity()
+	templates := make([]int32, 0, len(space.identityHandlers))
+
+	for template := range space.identityHandlers {
+		if identity&template == template {
+			templates = append(templates, template)
+		}
+	}
+
+	if space.deterministic {
+		sort.Slice(templates, func(i, j int) bool {
+			return templates[i] < templates[j]
+		})
+	}
+
+	for _, template := range templates {
+		space.identityHandlers[template].call(transition)
+	}
+}
+
+// call calls the callback of the handler
+// corresponding to the transition event.
+func (handler CollisionHandler) call(transition collisionTransition) {
+	var callback func(shape, other Shape)
+
+	switch transition.event {
+	case collisionBegin:
+		callback = handler.Begin
+
+	case collisionPersist:
+		callback = handler.Persist
+
+	case collisionEnd:
+		callback = handler.End
+	}
+
+	if callback != nil {
+		callback(transition.shape, transition.other)
+	}
+}
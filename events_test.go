@@ -0,0 +1,145 @@
+package cirno_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zergon321/cirno"
+)
+
+func TestSpaceStepEvents(t *testing.T) {
+	player, err := cirno.NewCircle(cirno.NewVector(10, 10), 2)
+	assert.Nil(t, err)
+	wall, err := cirno.NewRectangle(cirno.NewVector(14, 10), 4, 10, 0)
+	assert.Nil(t, err)
+	wall.SetIdentity(2)
+
+	space, err := cirno.NewSpace(2, 10, 64, 64,
+		cirno.NewVector(0, 0), cirno.NewVector(32, 32), false)
+	assert.Nil(t, err)
+	err = space.Add(player, wall)
+	assert.Nil(t, err)
+
+	events := []string{}
+	err = space.HandleShape(player, cirno.CollisionHandler{
+		Begin: func(shape, other cirno.Shape) {
+			assert.Equal(t, player, shape)
+			assert.Equal(t, wall, other)
+			events = append(events, "begin")
+		},
+		Persist: func(shape, other cirno.Shape) {
+			events = append(events, "persist")
+		},
+		End: func(shape, other cirno.Shape) {
+			events = append(events, "end")
+		},
+	})
+	assert.Nil(t, err)
+
+	wallEvents := 0
+	space.HandleIdentity(2, cirno.CollisionHandler{
+		Begin: func(shape, other cirno.Shape) {
+			assert.Equal(t, wall, shape)
+			assert.Equal(t, player, other)
+			wallEvents++
+		},
+	})
+
+	_, err = space.Step()
+	assert.Nil(t, err)
+	_, err = space.Step()
+	assert.Nil(t, err)
+
+	player.Move(cirno.NewVector(-5, 0))
+	_, err = space.Update(player)
+	assert.Nil(t, err)
+	collidingShapes, err := space.Step()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(collidingShapes))
+
+	_, err = space.Step()
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{"begin", "persist", "end"}, events)
+	assert.Equal(t, 1, wallEvents)
+
+	// Removing the shape ends its collisions.
+	player.Move(cirno.NewVector(5, 0))
+	_, err = space.Update(player)
+	assert.Nil(t, err)
+	_, err = space.Step()
+	assert.Nil(t, err)
+	err = space.Remove(wall)
+	assert.Nil(t, err)
+	_, err = space.Step()
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{"begin", "persist", "end", "begin", "end"}, events)
+	assert.Equal(t, 2, wallEvents)
+}
+
+func TestShapeHandlerOfRemovedShape(t *testing.T) {
+	player, err := cirno.NewCircle(cirno.NewVector(10, 10), 2)
+	assert.Nil(t, err)
+	wall, err := cirno.NewRectangle(cirno.NewVector(14, 10), 4, 10, 0)
+	assert.Nil(t, err)
+
+	space, err := cirno.NewSpace(2, 10, 64, 64,
+		cirno.NewVector(0, 0), cirno.NewVector(32, 32), false)
+	assert.Nil(t, err)
+	err = space.Add(player, wall)
+	assert.Nil(t, err)
+
+	events := []string{}
+	handler := cirno.CollisionHandler{
+		Begin: func(shape, other cirno.Shape) {
+			events = append(events, "begin")
+		},
+		End: func(shape, other cirno.Shape) {
+			events = append(events, "end")
+		},
+	}
+	err = space.HandleShape(player, handler)
+	assert.Nil(t, err)
+
+	_, err = space.Step()
+	assert.Nil(t, err)
+	snapshot, err := space.Snapshot()
+	assert.Nil(t, err)
+
+	// The handler of the removed shape is called for
+	// its last contacts and dropped after that.
+	err = space.Remove(player)
+	assert.Nil(t, err)
+	_, err = space.Step()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"begin", "end"}, events)
+
+	err = space.Add(player)
+	assert.Nil(t, err)
+	_, err = space.Step()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"begin", "end"}, events)
+
+	// The handlers are saved in the snapshot.
+	err = space.Restore(snapshot)
+	assert.Nil(t, err)
+	player.Move(cirno.NewVector(-5, 0))
+	_, err = space.Update(player)
+	assert.Nil(t, err)
+	_, err = space.Step()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"begin", "end", "end"}, events)
+
+	err = space.Clear()
+	assert.Nil(t, err)
+	err = space.Add(player, wall)
+	assert.Nil(t, err)
+	player.Move(cirno.NewVector(5, 0))
+	_, err = space.Update(player)
+	assert.Nil(t, err)
+	_, err = space.Step()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"begin", "end", "end"}, events)
+}
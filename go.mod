@@ -1,6 +1,6 @@
 module github.com/zergon321/cirno
 
-go 1.17
+go 1.18
 
 require (
 	github.com/faiface/pixel v0.10.0
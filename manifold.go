@@ -0,0 +1,413 @@
+package cirno
+
+import (
+	"fmt"
+	"math"
+)
+
+// FeatureID identifies the features of two shapes
+// that produced the contact point. It stays the same
+// while the shapes touch each other by the same features,
+// so it can be used to match contacts between frames.
+type FeatureID struct {
+	// ReferenceEdge is the index of the edge
+	// the contact point was clipped against.
+	ReferenceEdge int
+	// IncidentEdge is the index of the edge
+	// of the other shape the contact point belongs to.
+	IncidentEdge int
+	// IncidentVertex is the index of the incident
+	// edge vertex the contact point originates from.
+	IncidentVertex int
+	// Flipped is true if the reference edge
+	// belongs to the second shape.
+	Flipped bool
+}
+
+// ManifoldPoint is a single contact point
+// of the contact manifold.
+type ManifoldPoint struct {
+	Point Vector
+	Depth float64
+	ID    FeatureID
+}
+
+// Manifold describes the contact between two overlapping
+// shapes: the normal pointing from the first shape to
+// the second one and up to two contact points.
+type Manifold struct {
+	Normal Vector
+	Points []ManifoldPoint
+}
+
+// coreEdge is an edge of the convex core
+// with its outward normal.
+type coreEdge struct {
+	p      Vector
+	q      Vector
+	normal Vector
+}
+
+// referenceTolerance is used to prefer the edge of the
+// first shape as the reference one, so the manifold
+// doesn't flip between frames because of rounding errors.
+const referenceTolerance float64 = 0.0005
+
+// ManifoldFunc returns the contact manifold of
+// two shapes of the registered types.
+type ManifoldFunc func(one, other Shape) (Manifold, error)
+
+var manifoldFuncs = map[typePair]ManifoldFunc{}
+
+// RegisterManifoldFunc registers the function to compute the contact
+// manifold of the shapes of the specified types. The function is also
+// used for the reversed pair of types with the normal inverted unless
+// it's registered as well.
+//
+// Registration is not thread-safe and should be done
+// before the shapes of the types are used.
+func RegisterManifoldFunc(oneType, otherType string, fn ManifoldFunc) error {
+	id, err := registryKey(oneType, otherType)
+
+	if err != nil {
+		return err
+	}
+
+	if fn == nil {
+		return fmt.Errorf("the manifold function is nil")
+	}
+
+	manifoldFuncs[id] = fn
+
+	return nil
+}
+
+// ComputeManifold returns the contact manifold of two shapes.
+// If the shapes don't overlap, the manifold has no points.
+//
+// If no manifold function is registered for the user-defined
+// shapes, the manifold is assembled from the registered
+// collision, contact and penetration (or normal) functions.
+func ComputeManifold(one, other Shape) (Manifold, error) {
+	if one == nil {
+		return Manifold{}, fmt.Errorf("the first shape is nil")
+	}
+
+	if other == nil {
+		return Manifold{}, fmt.Errorf("the second shape is nil")
+	}
+
+	oneCore, oneBuiltin := coreOf(one)
+	otherCore, otherBuiltin := coreOf(other)
+
+	if oneBuiltin && otherBuiltin {
+		return manifoldCores(oneCore, otherCore), nil
+	}
+
+	if fn, exists := manifoldFuncs[typePair{one.TypeName(), other.TypeName()}]; exists {
+		return fn(one, other)
+	}
+
+	if fn, exists := manifoldFuncs[typePair{other.TypeName(), one.TypeName()}]; exists {
+		manifold, err := fn(other, one)
+
+		if err != nil {
+			return Manifold{}, err
+		}
+
+		return flipManifold(manifold), nil
+	}
+
+	return registeredManifold(one, other)
+}
+
+// flipManifold returns the manifold of the
+// shapes taken in the reversed order.
+func flipManifold(manifold Manifold) Manifold {
+	points := make([]ManifoldPoint, len(manifold.Points))
+
+	for i, point := range manifold.Points {
+		point.ID.Flipped = !point.ID.Flipped
+		points[i] = point
+	}
+
+	return Manifold{
+		Normal: manifold.Normal.MultiplyByScalar(-1),
+		Points: points,
+	}
+}
+
+// registeredManifold assembles the manifold of the user-defined
+// shapes from their contact points and the normal. The depth
+// of the points is known only if the penetration
+// function is registered for the shapes.
+func registeredManifold(one, other Shape) (Manifold, error) {
+	overlapped, err := ResolveCollision(one, other, false)
+
+	if err != nil {
+		return Manifold{}, err
+	}
+
+	if !overlapped {
+		return Manifold{Normal: Zero(), Points: []ManifoldPoint{}}, nil
+	}
+
+	normal, depth, _, err := Penetration(one, other)
+
+	if err != nil {
+		depth = 0
+		normal, err = NormalBetween(one, other)
+
+		if err != nil {
+			return Manifold{}, err
+		}
+	}
+
+	contacts, err := Contact(one, other)
+
+	if err != nil {
+		return Manifold{}, err
+	}
+
+	points := make([]ManifoldPoint, 0, 2)
+
+	for i, contact := range contacts {
+		if len(points) >= 2 {
+			break
+		}
+
+		points = append(points, ManifoldPoint{
+			Point: contact,
+			Depth: depth,
+			ID:    FeatureID{IncidentVertex: i},
+		})
+	}
+
+	return Manifold{
+		Normal: normal,
+		Points: points,
+	}, nil
+}
+
+// manifoldCores computes the contact manifold of two convex cores.
+func manifoldCores(a, b convexCore) Manifold {
+	normal, depth, ok := penetrationCores(a, b)
+
+	if !ok {
+		return Manifold{Normal: normal, Points: []ManifoldPoint{}}
+	}
+
+	if len(a.vertices) < 2 || len(b.vertices) < 2 {
+		return pointManifold(a, b, normal, depth)
+	}
+
+	aEdges := edgesOf(a.vertices)
+	bEdges := edgesOf(b.vertices)
+	aIndex := mostAlignedEdge(aEdges, normal)
+	bIndex := mostAlignedEdge(bEdges, normal.MultiplyByScalar(-1))
+	flipped := Dot(bEdges[bIndex].normal, normal.MultiplyByScalar(-1)) >
+		Dot(aEdges[aIndex].normal, normal)+referenceTolerance
+
+	var (
+		reference      coreEdge
+		referenceIndex int
+		incidentEdges  []coreEdge
+		referenceCore  convexCore
+		incidentCore   convexCore
+	)
+
+	if flipped {
+		reference = bEdges[bIndex]
+		referenceIndex = bIndex
+		incidentEdges = aEdges
+		referenceCore = b
+		incidentCore = a
+	} else {
+		reference = aEdges[aIndex]
+		referenceIndex = aIndex
+		incidentEdges = bEdges
+		referenceCore = a
+		incidentCore = b
+	}
+
+	incidentIndex := mostAlignedEdge(incidentEdges,
+		reference.normal.MultiplyByScalar(-1))
+	incident := incidentEdges[incidentIndex]
+
+	// Clip the incident edge against the side
+	// planes of the reference edge.
+	tangent, err := reference.q.Subtract(reference.p).Normalize()
+
+	if err != nil {
+		return pointManifold(a, b, normal, depth)
+	}
+
+	lower := Dot(tangent, reference.p)
+	upper := Dot(tangent, reference.q)
+	clipped := clipSegment(incident.p, incident.q, tangent, lower, upper)
+	radii := referenceCore.radius + incidentCore.radius
+	points := make([]ManifoldPoint, 0, 2)
+
+	for i, point := range clipped {
+		if math.IsNaN(point.X) {
+			continue
+		}
+
+		separation := Dot(point.Subtract(reference.p), reference.normal)
+
+		if separation > radii {
+			continue
+		}
+
+		// Place the contact point halfway between
+		// the surfaces of the shapes.
+		offset := (separation - referenceCore.radius + incidentCore.radius) / 2
+		points = append(points, ManifoldPoint{
+			Point: point.Subtract(reference.normal.MultiplyByScalar(offset)),
+			Depth: radii - separation,
+			ID: FeatureID{
+				ReferenceEdge:  referenceIndex,
+				IncidentEdge:   incidentIndex,
+				IncidentVertex: i,
+				Flipped:        flipped,
+			},
+		})
+	}
+
+	if len(points) == 0 {
+		return pointManifold(a, b, normal, depth)
+	}
+
+	manifoldNormal := reference.normal
+
+	if flipped {
+		manifoldNormal = manifoldNormal.MultiplyByScalar(-1)
+	}
+
+	return Manifold{
+		Normal: manifoldNormal,
+		Points: points,
+	}
+}
+
+// pointManifold returns the manifold with a single contact
+// point for the cores one of which is a point.
+func pointManifold(a, b convexCore, normal Vector, depth float64) Manifold {
+	closestA, closestB := closestPoints(a.vertices, b.vertices)
+	surfaceA := closestA.Add(normal.MultiplyByScalar(a.radius))
+	surfaceB := closestB.Subtract(normal.MultiplyByScalar(b.radius))
+	id := FeatureID{}
+
+	if len(a.vertices) > 1 {
+		id.ReferenceEdge = closestEdge(a.vertices, closestA)
+	} else if len(b.vertices) > 1 {
+		id.ReferenceEdge = closestEdge(b.vertices, closestB)
+		id.Flipped = true
+	}
+
+	return Manifold{
+		Normal: normal,
+		Points: []ManifoldPoint{{
+			Point: surfaceA.Add(surfaceB).MultiplyByScalar(0.5),
+			Depth: depth,
+			ID:    id,
+		}},
+	}
+}
+
+// edgesOf returns the edges of the convex set of vertices
+// with their outward normals.
+func edgesOf(vertices []Vector) []coreEdge {
+	orientation := 1.0
+
+	if signedArea(vertices) < 0 {
+		orientation = -1.0
+	}
+
+	edges := make([]coreEdge, 0, len(vertices))
+
+	for i := range vertices {
+		p := vertices[i]
+		q := vertices[(i+1)%len(vertices)]
+		normal, err := q.Subtract(p).PerpendicularClockwise().Normalize()
+
+		if err != nil {
+			normal = Zero()
+		}
+
+		edges = append(edges, coreEdge{
+			p:      p,
+			q:      q,
+			normal: normal.MultiplyByScalar(orientation),
+		})
+	}
+
+	return edges
+}
+
+// mostAlignedEdge returns the index of the edge whose
+// normal is the most aligned with the given direction.
+func mostAlignedEdge(edges []coreEdge, direction Vector) int {
+	index := 0
+	maxAlignment := math.Inf(-1)
+
+	for i, edge := range edges {
+		if alignment := Dot(edge.normal, direction); alignment > maxAlignment {
+			index = i
+			maxAlignment = alignment
+		}
+	}
+
+	return index
+}
+
+// closestEdge returns the index of the edge
+// closest to the given point.
+func closestEdge(vertices []Vector, point Vector) int {
+	index := 0
+	minSquaredDistance := math.Inf(1)
+
+	for i := range vertices {
+		candidate := closestPointOnSegment(vertices[i],
+			vertices[(i+1)%len(vertices)], point)
+
+		if sqrDistance := SquaredDistance(candidate, point); sqrDistance < minSquaredDistance {
+			index = i
+			minSquaredDistance = sqrDistance
+		}
+	}
+
+	return index
+}
+
+// clipSegment clips the segment from p to q so the projections
+// of its points onto the tangent lie between lower and upper.
+//
+// The point is NaN if it was clipped away completely.
+func clipSegment(p, q, tangent Vector, lower, upper float64) [2]Vector {
+	nan := NewVector(math.NaN(), math.NaN())
+	points := [2]Vector{p, q}
+	pProj := Dot(tangent, p)
+	qProj := Dot(tangent, q)
+
+	// The segment is completely outside.
+	if math.Max(pProj, qProj) < lower || math.Min(pProj, qProj) > upper {
+		return [2]Vector{nan, nan}
+	}
+
+	clip := func(bound float64) Vector {
+		t := (bound - pProj) / (qProj - pProj)
+
+		return p.Add(q.Subtract(p).MultiplyByScalar(t))
+	}
+
+	for i, projection := range [2]float64{pProj, qProj} {
+		if projection < lower {
+			points[i] = clip(lower)
+		} else if projection > upper {
+			points[i] = clip(upper)
+		}
+	}
+
+	return points
+}
@@ -0,0 +1,120 @@
+package cirno_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zergon321/cirno"
+)
+
+func TestManifoldRectangles(t *testing.T) {
+	ground, err := cirno.NewRectangle(cirno.NewVector(0, 0), 4, 4, 0)
+	assert.Nil(t, err)
+	box, err := cirno.NewRectangle(cirno.NewVector(1, 3.5), 4, 4, 0)
+	assert.Nil(t, err)
+
+	manifold, err := cirno.ComputeManifold(ground, box)
+	assert.Nil(t, err)
+	assert.True(t, manifold.Normal.ApproximatelyEqual(cirno.Up()))
+	assert.Equal(t, 2, len(manifold.Points))
+
+	for _, point := range manifold.Points {
+		assert.InDelta(t, 0.5, point.Depth, 0.0001)
+		assert.InDelta(t, 1.75, point.Point.Y, 0.0001)
+	}
+
+	xs := []float64{manifold.Points[0].Point.X, manifold.Points[1].Point.X}
+	assert.ElementsMatch(t, []float64{-1, 2}, xs)
+
+	// Feature IDs must be the same after a small movement.
+	ids := []cirno.FeatureID{manifold.Points[0].ID, manifold.Points[1].ID}
+	box.Move(cirno.NewVector(0.1, 0.05))
+	manifold, err = cirno.ComputeManifold(ground, box)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(manifold.Points))
+	assert.Equal(t, ids, []cirno.FeatureID{
+		manifold.Points[0].ID, manifold.Points[1].ID})
+}
+
+func TestManifoldCircle(t *testing.T) {
+	ground, err := cirno.NewRectangle(cirno.NewVector(0, 0), 4, 4, 0)
+	assert.Nil(t, err)
+	ball, err := cirno.NewCircle(cirno.NewVector(0.5, 2.5), 1)
+	assert.Nil(t, err)
+	far, err := cirno.NewCircle(cirno.NewVector(10, 10), 1)
+	assert.Nil(t, err)
+
+	manifold, err := cirno.ComputeManifold(ball, ground)
+	assert.Nil(t, err)
+	assert.True(t, manifold.Normal.ApproximatelyEqual(cirno.Down()))
+	assert.Equal(t, 1, len(manifold.Points))
+	assert.InDelta(t, 0.5, manifold.Points[0].Depth, 0.0001)
+	assert.True(t, manifold.Points[0].Point.ApproximatelyEqual(
+		cirno.NewVector(0.5, 1.75)))
+
+	manifold, err = cirno.ComputeManifold(ball, far)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(manifold.Points))
+}
+
+func TestManifoldCapsule(t *testing.T) {
+	ground, err := cirno.NewRectangle(cirno.NewVector(0, 0), 10, 2, 0)
+	assert.Nil(t, err)
+	capsule, err := cirno.NewCapsule(cirno.NewVector(-2, 1.5), cirno.NewVector(2, 1.5), 1)
+	assert.Nil(t, err)
+
+	manifold, err := cirno.ComputeManifold(ground, capsule)
+	assert.Nil(t, err)
+	assert.True(t, manifold.Normal.ApproximatelyEqual(cirno.Up()))
+	assert.Equal(t, 2, len(manifold.Points))
+
+	for _, point := range manifold.Points {
+		assert.InDelta(t, 0.5, point.Depth, 0.0001)
+		assert.InDelta(t, 0.75, point.Point.Y, 0.0001)
+	}
+}
+
+func TestManifoldUserDefinedShapes(t *testing.T) {
+	err := cirno.RegisterManifoldFunc("Rectangle", "Point",
+		func(one, other cirno.Shape) (cirno.Manifold, error) {
+			return cirno.Manifold{
+				Normal: cirno.Up(),
+				Points: []cirno.ManifoldPoint{{Point: other.Center()}},
+			}, nil
+		})
+	assert.Nil(t, err)
+
+	ground, err := cirno.NewRectangle(cirno.NewVector(0, 0), 4, 4, 0)
+	assert.Nil(t, err)
+	p := &point{position: cirno.NewVector(1, 1)}
+
+	// The registered function is used for the reversed pair.
+	manifold, err := cirno.ComputeManifold(p, ground)
+	assert.Nil(t, err)
+	assert.True(t, manifold.Normal.ApproximatelyEqual(cirno.Down()))
+	assert.Equal(t, 1, len(manifold.Points))
+	assert.True(t, manifold.Points[0].ID.Flipped)
+
+	// The manifold is assembled from the registered
+	// collision, contact and penetration functions.
+	d := &dot{point{position: cirno.NewVector(4, 4)}}
+	circle, err := cirno.NewCircle(cirno.NewVector(5, 4), 2)
+	assert.Nil(t, err)
+	far, err := cirno.NewCircle(cirno.NewVector(10, 10), 1)
+	assert.Nil(t, err)
+
+	manifold, err = cirno.ComputeManifold(d, circle)
+	assert.Nil(t, err)
+	assert.True(t, manifold.Normal.ApproximatelyEqual(cirno.Right()))
+	assert.Equal(t, 1, len(manifold.Points))
+	assert.InDelta(t, 1, manifold.Points[0].Depth, 0.0001)
+	assert.Equal(t, cirno.NewVector(4, 4), manifold.Points[0].Point)
+
+	manifold, err = cirno.ComputeManifold(circle, d)
+	assert.Nil(t, err)
+	assert.True(t, manifold.Normal.ApproximatelyEqual(cirno.Left()))
+
+	manifold, err = cirno.ComputeManifold(d, far)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(manifold.Points))
+}
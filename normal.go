@@ -21,9 +21,15 @@ func (circle *Circle) NormalTo(shape Shape) (Vector, error) {
 
 	case *Rectangle:
 		return circle.NormalToRectangle(other)
+
+	case *Polygon:
+		return circle.NormalToPolygon(other)
+
+	case *Capsule:
+		return circle.NormalToCapsule(other)
 	}
 
-	return Zero(), fmt.Errorf("unknown shape type")
+	return registeredNormal(circle, shape)
 }
 
 // NormalTo returns the normal from the given rectangle
@@ -42,9 +48,15 @@ func (rect *Rectangle) NormalTo(shape Shape) (Vector, error) {
 
 	case *Rectangle:
 		return rect.NormalToRectangle(other)
+
+	case *Polygon:
+		return rect.NormalToPolygon(other)
+
+	case *Capsule:
+		return rect.NormalToCapsule(other)
 	}
 
-	return Zero(), fmt.Errorf("unknown shape type")
+	return registeredNormal(rect, shape)
 }
 
 // NormalTo returns the normal from the given line to
@@ -63,9 +75,69 @@ func (line *Line) NormalTo(shape Shape) (Vector, error) {
 
 	case *Rectangle:
 		return line.NormalToRectangle(other)
+
+	case *Polygon:
+		return line.NormalToPolygon(other)
+
+	case *Capsule:
+		return line.NormalToCapsule(other)
+	}
+
+	return registeredNormal(line, shape)
+}
+
+// NormalTo returns the normal from the given polygon to
+// the other shape.
+func (polygon *Polygon) NormalTo(shape Shape) (Vector, error) {
+	if shape == nil {
+		return Zero(), fmt.Errorf("the shape is nil")
+	}
+
+	switch other := shape.(type) {
+	case *Circle:
+		return polygon.NormalToCircle(other)
+
+	case *Line:
+		return polygon.NormalToLine(other)
+
+	case *Rectangle:
+		return polygon.NormalToRectangle(other)
+
+	case *Polygon:
+		return polygon.NormalToPolygon(other)
+
+	case *Capsule:
+		return polygon.NormalToCapsule(other)
+	}
+
+	return registeredNormal(polygon, shape)
+}
+
+// NormalTo returns the normal from the given capsule to
+// the other shape.
+func (capsule *Capsule) NormalTo(shape Shape) (Vector, error) {
+	if shape == nil {
+		return Zero(), fmt.Errorf("the shape is nil")
+	}
+
+	switch other := shape.(type) {
+	case *Circle:
+		return capsule.NormalToCircle(other)
+
+	case *Line:
+		return capsule.NormalToLine(other)
+
+	case *Rectangle:
+		return capsule.NormalToRectangle(other)
+
+	case *Polygon:
+		return capsule.NormalToPolygon(other)
+
+	case *Capsule:
+		return capsule.NormalToCapsule(other)
 	}
 
-	return Zero(), fmt.Errorf("unknown shape type")
+	return registeredNormal(capsule, shape)
 }
 
 // NormalToCircle returns the normal from the given circle
@@ -417,3 +489,236 @@ func (rect *Rectangle) NormalToRectangle(other *Rectangle) (Vector, error) {
 
 	return normal, nil
 }
+
+// NormalToPolygon returns the normal from the given circle
+// to the polygon.
+func (circle *Circle) NormalToPolygon(polygon *Polygon) (Vector, error) {
+	if polygon == nil {
+		return Zero(), fmt.Errorf("the polygon is nil")
+	}
+
+	closestPoint := polygon.closestPoint(circle.center)
+
+	// If the center of the circle is inside the polygon,
+	// the normal points from the outline inwards.
+	if polygon.ContainsPoint(circle.center) {
+		return circle.center.Subtract(closestPoint).Normalize()
+	}
+
+	return closestPoint.Subtract(circle.center).Normalize()
+}
+
+// NormalToPolygon returns the normal from the given line
+// to the polygon.
+func (line *Line) NormalToPolygon(polygon *Polygon) (Vector, error) {
+	if polygon == nil {
+		return Zero(), fmt.Errorf("the polygon is nil")
+	}
+
+	return verticesNormal([]Vector{line.p, line.q},
+		polygon.Vertices()), nil
+}
+
+// NormalToPolygon returns the normal from the given rectangle
+// to the polygon.
+func (rect *Rectangle) NormalToPolygon(polygon *Polygon) (Vector, error) {
+	if polygon == nil {
+		return Zero(), fmt.Errorf("the polygon is nil")
+	}
+
+	vertices := rect.Vertices()
+
+	return verticesNormal(vertices[:], polygon.Vertices()), nil
+}
+
+// NormalToCircle returns the normal from the given polygon
+// to the circle.
+func (polygon *Polygon) NormalToCircle(circle *Circle) (Vector, error) {
+	if circle == nil {
+		return Zero(), fmt.Errorf("the circle is nil")
+	}
+
+	normalToPolygon, err := circle.NormalToPolygon(polygon)
+
+	if err != nil {
+		return Zero(), err
+	}
+
+	return normalToPolygon.MultiplyByScalar(-1), nil
+}
+
+// NormalToLine returns the normal from the given polygon
+// to the line.
+func (polygon *Polygon) NormalToLine(line *Line) (Vector, error) {
+	if line == nil {
+		return Zero(), fmt.Errorf("the line is nil")
+	}
+
+	return verticesNormal(polygon.Vertices(),
+		[]Vector{line.p, line.q}), nil
+}
+
+// NormalToRectangle returns the normal from the given polygon
+// to the rectangle.
+func (polygon *Polygon) NormalToRectangle(rect *Rectangle) (Vector, error) {
+	if rect == nil {
+		return Zero(), fmt.Errorf("the rectangle is nil")
+	}
+
+	vertices := rect.Vertices()
+
+	return verticesNormal(polygon.Vertices(), vertices[:]), nil
+}
+
+// NormalToPolygon returns the normal from the given polygon
+// to the other polygon.
+func (polygon *Polygon) NormalToPolygon(other *Polygon) (Vector, error) {
+	if other == nil {
+		return Zero(), fmt.Errorf("the polygon is nil")
+	}
+
+	return verticesNormal(polygon.Vertices(), other.Vertices()), nil
+}
+
+// verticesNormal returns the normal from the first convex
+// set of vertices to the second one. The normal is the axis
+// of the greatest separation or, if the sets overlap,
+// of the least penetration.
+func verticesNormal(a, b []Vector) Vector {
+	axes := append(edgeAxes(a), edgeAxes(b)...)
+	normal := Zero()
+	maxSeparation := math.Inf(-1)
+
+	for _, axis := range axes {
+		aMin, aMax := projectVertices(a, axis)
+		bMin, bMax := projectVertices(b, axis)
+
+		// B is ahead of A along the axis.
+		if separation := bMin - aMax; separation > maxSeparation {
+			normal = axis
+			maxSeparation = separation
+		}
+
+		// B is behind A along the axis.
+		if separation := aMin - bMax; separation > maxSeparation {
+			normal = axis.MultiplyByScalar(-1)
+			maxSeparation = separation
+		}
+	}
+
+	return normal
+}
+
+// NormalToCapsule returns the normal from the given circle
+// to the capsule.
+func (circle *Circle) NormalToCapsule(capsule *Capsule) (Vector, error) {
+	if capsule == nil {
+		return Zero(), fmt.Errorf("the capsule is nil")
+	}
+
+	return segmentsNormal([]Vector{circle.center}, capsule.segment())
+}
+
+// NormalToCapsule returns the normal from the given line
+// to the capsule.
+func (line *Line) NormalToCapsule(capsule *Capsule) (Vector, error) {
+	if capsule == nil {
+		return Zero(), fmt.Errorf("the capsule is nil")
+	}
+
+	return segmentsNormal([]Vector{line.p, line.q}, capsule.segment())
+}
+
+// NormalToCapsule returns the normal from the given rectangle
+// to the capsule.
+func (rect *Rectangle) NormalToCapsule(capsule *Capsule) (Vector, error) {
+	if capsule == nil {
+		return Zero(), fmt.Errorf("the capsule is nil")
+	}
+
+	vertices := rect.Vertices()
+
+	return segmentsNormal(vertices[:], capsule.segment())
+}
+
+// NormalToCapsule returns the normal from the given polygon
+// to the capsule.
+func (polygon *Polygon) NormalToCapsule(capsule *Capsule) (Vector, error) {
+	if capsule == nil {
+		return Zero(), fmt.Errorf("the capsule is nil")
+	}
+
+	return segmentsNormal(polygon.Vertices(), capsule.segment())
+}
+
+// NormalToCircle returns the normal from the given capsule
+// to the circle.
+func (capsule *Capsule) NormalToCircle(circle *Circle) (Vector, error) {
+	if circle == nil {
+		return Zero(), fmt.Errorf("the circle is nil")
+	}
+
+	return segmentsNormal(capsule.segment(), []Vector{circle.center})
+}
+
+// NormalToLine returns the normal from the given capsule
+// to the line.
+func (capsule *Capsule) NormalToLine(line *Line) (Vector, error) {
+	if line == nil {
+		return Zero(), fmt.Errorf("the line is nil")
+	}
+
+	return segmentsNormal(capsule.segment(), []Vector{line.p, line.q})
+}
+
+// NormalToRectangle returns the normal from the given capsule
+// to the rectangle.
+func (capsule *Capsule) NormalToRectangle(rect *Rectangle) (Vector, error) {
+	if rect == nil {
+		return Zero(), fmt.Errorf("the rectangle is nil")
+	}
+
+	vertices := rect.Vertices()
+
+	return segmentsNormal(capsule.segment(), vertices[:])
+}
+
+// NormalToPolygon returns the normal from the given capsule
+// to the polygon.
+func (capsule *Capsule) NormalToPolygon(polygon *Polygon) (Vector, error) {
+	if polygon == nil {
+		return Zero(), fmt.Errorf("the polygon is nil")
+	}
+
+	return segmentsNormal(capsule.segment(), polygon.Vertices())
+}
+
+// NormalToCapsule returns the normal from the given capsule
+// to the other capsule.
+func (capsule *Capsule) NormalToCapsule(other *Capsule) (Vector, error) {
+	if other == nil {
+		return Zero(), fmt.Errorf("the capsule is nil")
+	}
+
+	return segmentsNormal(capsule.segment(), other.segment())
+}
+
+// segmentsNormal returns the normal from the first convex
+// set of vertices to the second one taking the direction
+// between their closest points. If the sets overlap,
+// the axis of the least penetration is used instead.
+func segmentsNormal(a, b []Vector) (Vector, error) {
+	distance, closestA, closestB := verticesDistance(a, b)
+
+	if distance > Epsilon {
+		return closestB.Subtract(closestA).Normalize()
+	}
+
+	normal := verticesNormal(a, b)
+
+	if normal == Zero() {
+		return Zero(), fmt.Errorf("couldn't compute the normal")
+	}
+
+	return normal, nil
+}
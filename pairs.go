@@ -0,0 +1,180 @@
+package cirno
+
+// CollisionPair is the pair of colliding shapes.
+type CollisionPair struct {
+	One   Shape
+	Other Shape
+	// OneCollides is true if the tags of the first
+	// shape allow it to collide the second one.
+	OneCollides bool
+	// OtherCollides is true if the tags of the second
+	// shape allow it to collide the first one.
+	OtherCollides bool
+}
+
+// CollisionPairs returns all the pairs of colliding shapes.
+// Each unordered pair is returned and tested only once.
+//
+// If the space uses tags, the pair is returned when
+// the tags of at least one of the shapes allow the collision.
+// Otherwise both the directions of the pair are set.
+func (space *Space) CollisionPairs() ([]CollisionPair, error) {
+	pairs := []CollisionPair{}
+	err := space.EachCollisionPair(func(pair CollisionPair) error {
+		pairs = append(pairs, pair)
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return pairs, nil
+}
+
+// EachCollisionPair calls the function for every pair of colliding
+// shapes the same way as CollisionPairs, but the pairs are not
+// collected unless the space is deterministic, then the pairs
+// are sorted by the insertion order first.
+// The iteration stops on the first error.
+//
+// The built-in broadphases reuse their buffers, so the number of
+// memory allocations doesn't depend on the number of the shapes
+// unless the space is deterministic or wrapped.
+//
+// The space must not be changed by the function.
+func (space *Space) EachCollisionPair(fn func(pair CollisionPair) error) error {
+	if space.deterministic {
+		return space.eachOrderedCollisionPair(fn)
+	}
+
+	var reported map[shapePair]none
+	wrapped := space.boundsPolicy == BoundsWrap
+
+	// Only the wrapped space can find
+	// the same pair more than once.
+	if wrapped {
+		reported = map[shapePair]none{}
+	}
+
+	err := space.pairs(func(one, other Shape) error {
+		pair, ok, err := space.collisionPair(one, one, other)
+
+		if err != nil || !ok {
+			return err
+		}
+
+		if wrapped {
+			reported[shapePair{one, other}] = none{}
+		}
+
+		return fn(pair)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return space.wrappedPairs(func(one, image, other Shape) error {
+		if _, ok := reported[shapePair{one, other}]; ok {
+			return nil
+		}
+
+		if _, ok := reported[shapePair{other, one}]; ok {
+			return nil
+		}
+
+		pair, ok, err := space.collisionPair(one, image, other)
+
+		if err != nil || !ok {
+			return err
+		}
+
+		reported[shapePair{one, other}] = none{}
+
+		return fn(pair)
+	})
+}
+
+// eachOrderedCollisionPair collects the pairs of shapes which might
+// collide, sorts them in the order of insertion and calls the function
+// for every pair of colliding shapes.
+func (space *Space) eachOrderedCollisionPair(fn func(pair CollisionPair) error) error {
+	candidates, err := space.candidates()
+
+	if err != nil {
+		return err
+	}
+
+	reported := map[shapePair]none{}
+
+	for _, candidate := range candidates {
+		if _, ok := reported[shapePair{candidate.shape, candidate.other}]; ok {
+			continue
+		}
+
+		if _, ok := reported[shapePair{candidate.other, candidate.shape}]; ok {
+			continue
+		}
+
+		pair, ok, err := space.collisionPair(candidate.shape,
+			candidate.image, candidate.other)
+
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			continue
+		}
+
+		reported[shapePair{candidate.shape, candidate.other}] = none{}
+
+		if err := fn(pair); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// collisionPair returns the pair of shapes and true if
+// the image of the first shape collides the second one.
+func (space *Space) collisionPair(one, image, other Shape) (CollisionPair, bool, error) {
+	pair := CollisionPair{
+		One:           one,
+		Other:         other,
+		OneCollides:   true,
+		OtherCollides: true,
+	}
+
+	if space.useTags {
+		oneCollides, err := one.ShouldCollide(other)
+
+		if err != nil {
+			return pair, false, err
+		}
+
+		otherCollides, err := other.ShouldCollide(one)
+
+		if err != nil {
+			return pair, false, err
+		}
+
+		if !oneCollides && !otherCollides {
+			return pair, false, nil
+		}
+
+		pair.OneCollides = oneCollides
+		pair.OtherCollides = otherCollides
+	}
+
+	overlapped, err := ResolveCollision(image, other, false)
+
+	if err != nil {
+		return pair, false, err
+	}
+
+	return pair, overlapped, nil
+}
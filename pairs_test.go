@@ -0,0 +1,106 @@
+package cirno_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zergon321/cirno"
+)
+
+func TestCollisionPairs(t *testing.T) {
+	space, err := cirno.NewSpace(2, 1, 40, 40, cirno.NewVector(-20, -20),
+		cirno.NewVector(20, 20), true)
+	assert.Nil(t, err)
+
+	hunter, err := cirno.NewCircle(cirno.NewVector(0, 0), 2)
+	assert.Nil(t, err)
+	hunter.SetIdentity(1)
+	hunter.SetMask(2)
+	prey, err := cirno.NewCircle(cirno.NewVector(1, 0), 2)
+	assert.Nil(t, err)
+	prey.SetIdentity(2)
+	ghost, err := cirno.NewCircle(cirno.NewVector(0, 1), 2)
+	assert.Nil(t, err)
+	ghost.SetIdentity(4)
+	err = space.Add(hunter, prey, ghost)
+	assert.Nil(t, err)
+
+	// The ghost collides nothing by tags.
+	pairs, err := space.CollisionPairs()
+	assert.Nil(t, err)
+	assert.Len(t, pairs, 1)
+
+	pair := pairs[0]
+
+	if pair.One == prey {
+		pair.One, pair.Other = pair.Other, pair.One
+		pair.OneCollides, pair.OtherCollides =
+			pair.OtherCollides, pair.OneCollides
+	}
+
+	assert.Equal(t, hunter, pair.One)
+	assert.Equal(t, prey, pair.Other)
+	assert.True(t, pair.OneCollides)
+	assert.False(t, pair.OtherCollides)
+
+	count := 0
+	err = space.EachCollisionPair(func(pair cirno.CollisionPair) error {
+		count++
+
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestWrappedCollisionPairs(t *testing.T) {
+	space, err := cirno.NewSpace(2, 4, 40, 40, cirno.NewVector(0, 0),
+		cirno.NewVector(10, 10), false, cirno.WithBoundsPolicy(cirno.BoundsWrap))
+	assert.Nil(t, err)
+
+	left, err := cirno.NewCircle(cirno.NewVector(0.5, 5), 1)
+	assert.Nil(t, err)
+	right, err := cirno.NewCircle(cirno.NewVector(9.5, 5), 1)
+	assert.Nil(t, err)
+	err = space.Add(left, right)
+	assert.Nil(t, err)
+
+	// Both the images find the same pair.
+	pairs, err := space.CollisionPairs()
+	assert.Nil(t, err)
+	assert.Len(t, pairs, 1)
+	assert.True(t, pairs[0].OneCollides)
+	assert.True(t, pairs[0].OtherCollides)
+}
+
+func TestEachCollisionPairAllocations(t *testing.T) {
+	space, err := cirno.NewSpace(5, 4, 200, 200, cirno.NewVector(-100, -100),
+		cirno.NewVector(100, 100), false)
+	assert.Nil(t, err)
+
+	random := rand.New(rand.NewSource(123))
+
+	for i := 0; i < 400; i++ {
+		circle, err := cirno.NewCircle(cirno.NewVector(
+			random.Float64()*200-100, random.Float64()*200-100), 2)
+		assert.Nil(t, err)
+		err = space.Add(circle)
+		assert.Nil(t, err)
+	}
+
+	count := 0
+	countPair := func(pair cirno.CollisionPair) error {
+		count++
+
+		return nil
+	}
+
+	allocs := testing.AllocsPerRun(10, func() {
+		space.EachCollisionPair(countPair)
+	})
+
+	assert.NotZero(t, count)
+	assert.LessOrEqual(t, allocs, 8.0)
+}
@@ -0,0 +1,183 @@
+package cirno
+
+import (
+	"fmt"
+	"sync"
+)
+
+// CollidingShapesParallel returns the same dictionary of
+// colliding shapes as CollidingShapes, but the collision
+// detection is done by the specified number of goroutines.
+//
+// The leaves of the quad tree (or the cells of the spatial hash)
+// are distributed among the workers, and every worker finds and
+// tests the pairs of shapes within its leaves. Every pair is
+// found only in the first leaf both the shapes share, so it's
+// tested only once. The dynamic shapes are distributed among
+// the workers to be tested against the static ones.
+//
+// If the broadphase can't be split into leaves or the space
+// is wrapped, the pairs of shapes are found by the broadphase
+// first, and only the exact collision detection is parallel.
+//
+// The custom broadphase of the static shapes
+// must allow concurrent queries.
+func (space *Space) CollidingShapesParallel(workers int) (map[Shape]Shapes, error) {
+	if workers <= 0 {
+		return nil, fmt.Errorf("the number of workers must be positive")
+	}
+
+	index, ok := space.index.(partitionedBroadphase)
+
+	if !ok || space.boundsPolicy == BoundsWrap {
+		return space.resolveParallel(workers)
+	}
+
+	parts := index.partitions(workers)
+	dynamics := make([]Shape, 0, len(space.kinds))
+
+	for shape, kind := range space.kinds {
+		if kind == BodyDynamic && space.awake(shape) {
+			dynamics = append(dynamics, shape)
+		}
+	}
+
+	collisions := make([][]candidate, len(parts))
+	errs := make([]error, len(parts))
+	wg := &sync.WaitGroup{}
+
+	for i, part := range parts {
+		start := i * len(dynamics) / len(parts)
+		end := (i + 1) * len(dynamics) / len(parts)
+
+		wg.Add(1)
+
+		go func(i int, part func(fn func(one, other Shape) error) error, dynamics []Shape) {
+			defer wg.Done()
+
+			collisions[i], errs[i] = space.collidingPart(part, dynamics)
+		}(i, part, dynamics[start:end])
+	}
+
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	candidates := []candidate{}
+
+	for _, colliding := range collisions {
+		candidates = append(candidates, colliding...)
+	}
+
+	overlaps := make([]bool, len(candidates))
+
+	for i := range overlaps {
+		overlaps[i] = true
+	}
+
+	return collidingShapesOf(candidates, overlaps), nil
+}
+
+// collidingPart returns the colliding pairs of shapes found
+// in the part of the broadphase and the colliding pairs
+// of the dynamic shapes and the static ones.
+func (space *Space) collidingPart(part func(fn func(one, other Shape) error) error, dynamics []Shape) ([]candidate, error) {
+	colliding := []candidate{}
+	resolve := func(shape, otherShape Shape) error {
+		overlapped, err := ResolveCollision(shape, otherShape, space.useTags)
+
+		if err != nil {
+			return err
+		}
+
+		if overlapped {
+			colliding = append(colliding, candidate{
+				shape: shape,
+				image: shape,
+				other: otherShape,
+			})
+		}
+
+		return nil
+	}
+
+	err := part(func(shape, otherShape Shape) error {
+		if !space.paired(shape, otherShape) {
+			return nil
+		}
+
+		return resolve(shape, otherShape)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, shape := range dynamics {
+		err := space.staticPairs(shape, func(otherShape Shape) error {
+			return resolve(shape, otherShape)
+		})
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return colliding, nil
+}
+
+// resolveParallel finds the pairs of shapes which might collide
+// and distributes them among the workers evenly to detect
+// the collisions.
+func (space *Space) resolveParallel(workers int) (map[Shape]Shapes, error) {
+	candidates, err := space.candidates()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if workers > len(candidates) {
+		workers = len(candidates)
+	}
+
+	overlaps := make([]bool, len(candidates))
+	errs := make([]error, workers)
+	wg := &sync.WaitGroup{}
+
+	for worker := 0; worker < workers; worker++ {
+		start := worker * len(candidates) / workers
+		end := (worker + 1) * len(candidates) / workers
+
+		wg.Add(1)
+
+		go func(worker, start, end int) {
+			defer wg.Done()
+
+			for i := start; i < end; i++ {
+				overlapped, err := candidates[i].resolve(space.useTags)
+
+				if err != nil {
+					errs[worker] = err
+
+					return
+				}
+
+				overlaps[i] = overlapped
+			}
+		}(worker, start, end)
+	}
+
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return collidingShapesOf(candidates, overlaps), nil
+}
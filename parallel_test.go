@@ -0,0 +1,93 @@
+package cirno_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zergon321/cirno"
+)
+
+// randomSpace fills the space with the random
+// circles and rectangles within the box of the size.
+func randomSpace(t testing.TB, space *cirno.Space, count int, size float64, seed int64) {
+	random := rand.New(rand.NewSource(seed))
+
+	for i := 0; i < count; i++ {
+		pos := cirno.NewVector(random.Float64()*size-size/2,
+			random.Float64()*size-size/2)
+		var (
+			shape cirno.Shape
+			err   error
+		)
+
+		if i%2 == 0 {
+			shape, err = cirno.NewCircle(pos, random.Float64()*3+0.5)
+		} else {
+			shape, err = cirno.NewRectangle(pos, random.Float64()*5+0.5,
+				random.Float64()*5+0.5, random.Float64()*360)
+		}
+
+		assert.Nil(t, err)
+
+		kind := cirno.BodyDynamic
+
+		if i%5 == 0 {
+			kind = cirno.BodyStatic
+		}
+
+		err = space.AddAs(kind, shape)
+		assert.Nil(t, err)
+	}
+}
+
+func TestCollidingShapesParallel(t *testing.T) {
+	hash, err := cirno.NewSpatialHash(8)
+	assert.Nil(t, err)
+	tree, err := cirno.NewAABBTree(0.5)
+	assert.Nil(t, err)
+
+	for _, options := range [][]cirno.SpaceOption{
+		nil,
+		{cirno.WithBroadphase(hash)},
+		{cirno.WithBroadphase(tree)},
+	} {
+		space, err := cirno.NewSpace(4, 4, 100, 100, cirno.NewVector(-50, -50),
+			cirno.NewVector(50, 50), false, options...)
+		assert.Nil(t, err)
+		randomSpace(t, space, 300, 100, 321)
+
+		expected, err := space.CollidingShapes()
+		assert.Nil(t, err)
+		assert.NotEmpty(t, expected)
+
+		for _, workers := range []int{1, 3, 8, 1000} {
+			actual, err := space.CollidingShapesParallel(workers)
+			assert.Nil(t, err)
+			assert.Equal(t, expected, actual)
+		}
+
+		_, err = space.CollidingShapesParallel(0)
+		assert.NotNil(t, err)
+	}
+}
+
+func BenchmarkCollidingShapes(b *testing.B) {
+	space, err := cirno.NewSpace(6, 8, 1000, 1000, cirno.NewVector(-500, -500),
+		cirno.NewVector(500, 500), false)
+	assert.Nil(b, err)
+	randomSpace(b, space, 10000, 1000, 123)
+
+	b.Run("sequential", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			space.CollidingShapes()
+		}
+	})
+
+	b.Run("parallel", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			space.CollidingShapesParallel(8)
+		}
+	})
+}
@@ -0,0 +1,173 @@
+package cirno
+
+import (
+	"fmt"
+	"math"
+)
+
+// PenetrationFunc returns the normal and the depth of penetration
+// between two shapes of the registered types.
+type PenetrationFunc func(one, other Shape) (Vector, float64, bool, error)
+
+var penetrationFuncs = map[typePair]PenetrationFunc{}
+
+// convexCore is the convex set of vertices which forms
+// the shape being inflated by the radius.
+//
+// A circle is a point inflated by its radius, a capsule
+// is a segment inflated by its radius, lines and
+// polygons are not inflated at all.
+type convexCore struct {
+	vertices []Vector
+	radius   float64
+}
+
+// coreOf returns the convex core of the built-in shape.
+func coreOf(shape Shape) (convexCore, bool) {
+	switch other := shape.(type) {
+	case *Circle:
+		return convexCore{
+			vertices: []Vector{other.center},
+			radius:   other.radius,
+		}, true
+
+	case *Line:
+		return convexCore{
+			vertices: []Vector{other.p, other.q},
+		}, true
+
+	case *Rectangle:
+		vertices := other.Vertices()
+
+		return convexCore{
+			vertices: vertices[:],
+		}, true
+
+	case *Polygon:
+		return convexCore{
+			vertices: other.Vertices(),
+		}, true
+
+	case *Capsule:
+		return convexCore{
+			vertices: other.segment(),
+			radius:   other.radius,
+		}, true
+	}
+
+	return convexCore{}, false
+}
+
+// RegisterPenetrationFunc registers the function to compute penetration
+// between the shapes of the specified types. The function is also used
+// for the reversed pair of types with the normal inverted unless it's
+// registered as well.
+//
+// Registration is not thread-safe and should be done
+// before the shapes of the types are used.
+func RegisterPenetrationFunc(oneType, otherType string, fn PenetrationFunc) error {
+	id, err := registryKey(oneType, otherType)
+
+	if err != nil {
+		return err
+	}
+
+	if fn == nil {
+		return fmt.Errorf("the penetration function is nil")
+	}
+
+	penetrationFuncs[id] = fn
+
+	return nil
+}
+
+// Penetration returns the minimum translation of the overlapping
+// shapes: the normal pointing from the first shape to the second
+// one and the depth of penetration along the normal.
+//
+// Moving the second shape by the normal multiplied by the depth
+// (or the first shape by the opposite vector) resolves the overlap.
+// If the shapes don't overlap, ok is false.
+func Penetration(one, other Shape) (normal Vector, depth float64, ok bool, err error) {
+	if one == nil {
+		return Zero(), 0, false,
+			fmt.Errorf("the first shape is nil")
+	}
+
+	if other == nil {
+		return Zero(), 0, false,
+			fmt.Errorf("the second shape is nil")
+	}
+
+	oneCore, oneBuiltin := coreOf(one)
+	otherCore, otherBuiltin := coreOf(other)
+
+	if oneBuiltin && otherBuiltin {
+		normal, depth, ok = penetrationCores(oneCore, otherCore)
+
+		return normal, depth, ok, nil
+	}
+
+	if fn, exists := penetrationFuncs[typePair{one.TypeName(), other.TypeName()}]; exists {
+		return fn(one, other)
+	}
+
+	if fn, exists := penetrationFuncs[typePair{other.TypeName(), one.TypeName()}]; exists {
+		normal, depth, ok, err = fn(other, one)
+
+		return normal.MultiplyByScalar(-1), depth, ok, err
+	}
+
+	return Zero(), 0, false, fmt.Errorf(
+		"unknown shape type combination: '%s' and '%s'",
+		one.TypeName(), other.TypeName())
+}
+
+// penetrationCores returns the normal from the first core
+// to the second one and the depth of their penetration.
+func penetrationCores(a, b convexCore) (Vector, float64, bool) {
+	radii := a.radius + b.radius
+	distance, closestA, closestB := verticesDistance(a.vertices, b.vertices)
+
+	// If the cores don't overlap, only their
+	// inflated parts can penetrate each other.
+	if distance > Epsilon {
+		if distance >= radii {
+			return Zero(), 0, false
+		}
+
+		normal := closestB.Subtract(closestA).
+			MultiplyByScalar(1.0 / distance)
+
+		return normal, radii - distance, true
+	}
+
+	// Otherwise find the axis of the least
+	// penetration using the separating axis theorem.
+	axes := append(edgeAxes(a.vertices), edgeAxes(b.vertices)...)
+	normal := Up()
+	minOverlap := math.Inf(1)
+
+	for _, axis := range axes {
+		aMin, aMax := projectVertices(a.vertices, axis)
+		bMin, bMax := projectVertices(b.vertices, axis)
+
+		// Push B forward along the axis.
+		if overlap := aMax - bMin; overlap < minOverlap {
+			normal = axis
+			minOverlap = overlap
+		}
+
+		// Push B backward along the axis.
+		if overlap := bMax - aMin; overlap < minOverlap {
+			normal = axis.MultiplyByScalar(-1)
+			minOverlap = overlap
+		}
+	}
+
+	if math.IsInf(minOverlap, 1) {
+		minOverlap = 0
+	}
+
+	return normal, minOverlap + radii, true
+}
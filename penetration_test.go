@@ -0,0 +1,83 @@
+package cirno_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zergon321/cirno"
+)
+
+func TestPenetrationCircles(t *testing.T) {
+	c1, err := cirno.NewCircle(cirno.NewVector(0, 0), 2)
+	assert.Nil(t, err)
+	c2, err := cirno.NewCircle(cirno.NewVector(3, 0), 2)
+	assert.Nil(t, err)
+	c3, err := cirno.NewCircle(cirno.NewVector(10, 0), 2)
+	assert.Nil(t, err)
+
+	normal, depth, ok, err := cirno.Penetration(c1, c2)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.True(t, normal.ApproximatelyEqual(cirno.Right()))
+	assert.InDelta(t, 1, depth, 0.0001)
+
+	_, _, ok, err = cirno.Penetration(c1, c3)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+}
+
+func TestPenetrationRectangles(t *testing.T) {
+	r1, err := cirno.NewRectangle(cirno.NewVector(0, 0), 4, 4, 0)
+	assert.Nil(t, err)
+	r2, err := cirno.NewRectangle(cirno.NewVector(1, 3.5), 4, 4, 0)
+	assert.Nil(t, err)
+
+	normal, depth, ok, err := cirno.Penetration(r1, r2)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.True(t, normal.ApproximatelyEqual(cirno.Up()))
+	assert.InDelta(t, 0.5, depth, 0.0001)
+
+	// Resolve the overlap.
+	r2.Move(normal.MultiplyByScalar(depth + cirno.Epsilon))
+	overlapped, err := cirno.ResolveCollision(r1, r2, false)
+	assert.Nil(t, err)
+	assert.False(t, overlapped)
+}
+
+func TestPenetrationMixed(t *testing.T) {
+	rect, err := cirno.NewRectangle(cirno.NewVector(0, 0), 4, 4, 0)
+	assert.Nil(t, err)
+	circle, err := cirno.NewCircle(cirno.NewVector(-2.5, 0.5), 1)
+	assert.Nil(t, err)
+	inner, err := cirno.NewCircle(cirno.NewVector(1.5, 0), 1)
+	assert.Nil(t, err)
+	line, err := cirno.NewLine(cirno.NewVector(-1, -1.8), cirno.NewVector(1, -3))
+	assert.Nil(t, err)
+	capsule, err := cirno.NewCapsule(cirno.NewVector(-1, 2.5), cirno.NewVector(1, 2.5), 1)
+	assert.Nil(t, err)
+
+	normal, depth, ok, err := cirno.Penetration(rect, circle)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.True(t, normal.ApproximatelyEqual(cirno.Left()))
+	assert.InDelta(t, 0.5, depth, 0.0001)
+
+	normal, depth, ok, err = cirno.Penetration(inner, rect)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.True(t, normal.ApproximatelyEqual(cirno.Left()))
+	assert.InDelta(t, 1.5, depth, 0.0001)
+
+	normal, depth, ok, err = cirno.Penetration(rect, line)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.True(t, normal.ApproximatelyEqual(cirno.Down()))
+	assert.InDelta(t, 0.2, depth, 0.0001)
+
+	normal, depth, ok, err = cirno.Penetration(capsule, rect)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.True(t, normal.ApproximatelyEqual(cirno.Down()))
+	assert.InDelta(t, 0.5, depth, 0.0001)
+}
@@ -0,0 +1,230 @@
+package cirno
+
+import (
+	"fmt"
+	"math"
+)
+
+// Polygon represents a convex polygon whose
+// vertices are defined in its local space.
+type Polygon struct {
+	center   Vector
+	vertices []Vector
+	angle    float64
+	tag
+	data
+	domain
+}
+
+// TypeName returns the name of the shape type.
+func (p *Polygon) TypeName() string {
+	return "Polygon"
+}
+
+// Center returns the coordinates of the origin
+// of the polygon's local space.
+func (p *Polygon) Center() Vector {
+	return p.center
+}
+
+// Angle returns the angle of the polygon (in degrees).
+func (p *Polygon) Angle() float64 {
+	return p.angle
+}
+
+// AngleRadians returns the angle of the polygon (in radians).
+func (p *Polygon) AngleRadians() float64 {
+	return p.angle * DegToRad
+}
+
+// Move moves the polygon in the specified direction; returns its new position.
+func (p *Polygon) Move(direction Vector) Vector {
+	p.center = p.center.Add(direction)
+
+	return p.center
+}
+
+// SetPosition sets the position of the polygon to the given coordinates.
+func (p *Polygon) SetPosition(pos Vector) Vector {
+	p.center = pos
+
+	return p.center
+}
+
+// Rotate rotates the whole polygon at the specified angle (in degrees).
+//
+// Returns the new angle of the polygon (in degrees).
+func (p *Polygon) Rotate(angle float64) float64 {
+	p.angle += angle
+	p.angle = AdjustAngle(p.angle)
+
+	return p.angle
+}
+
+// RotateRadians rotates the whole polygon at the specified angle (in radians).
+//
+// Returns the new angle of the polygon (in radians).
+func (p *Polygon) RotateRadians(angle float64) float64 {
+	return p.Rotate(angle*RadToDeg) * DegToRad
+}
+
+// RotateAround rotates the polygon around the specified base point.
+func (p *Polygon) RotateAround(angle float64, base Vector) Vector {
+	p.center = p.center.RotateAround(angle, base)
+
+	return p.center
+}
+
+// RotateAroundRadians rotates the polygon around the specified base point
+// at the angle in radians.
+func (p *Polygon) RotateAroundRadians(angle float64, base Vector) Vector {
+	p.center = p.center.RotateAroundRadians(angle, base)
+
+	return p.center
+}
+
+// SetAngle sets the angle of the polygon to the
+// given value (in degrees).
+func (p *Polygon) SetAngle(angle float64) float64 {
+	return p.Rotate(angle - p.angle)
+}
+
+// SetAngleRadians sets the angle of the polygon to the
+// given value (in radians).
+func (p *Polygon) SetAngleRadians(angle float64) float64 {
+	return p.RotateRadians(angle - p.AngleRadians())
+}
+
+// ContainsPoint detects if the given point is inside the polygon.
+func (p *Polygon) ContainsPoint(point Vector) bool {
+	vertices := p.Vertices()
+
+	for i := range vertices {
+		a := vertices[i]
+		b := vertices[(i+1)%len(vertices)]
+
+		if Cross(b.Subtract(a), point.Subtract(a)) < -Epsilon {
+			return false
+		}
+	}
+
+	return true
+}
+
+// LocalVertices returns the vertices of the polygon
+// in its local space in counter-clockwise order.
+func (p *Polygon) LocalVertices() []Vector {
+	vertices := make([]Vector, len(p.vertices))
+	copy(vertices, p.vertices)
+
+	return vertices
+}
+
+// Vertices returns the vertices of the polygon
+// in the world space in counter-clockwise order.
+func (p *Polygon) Vertices() []Vector {
+	vertices := make([]Vector, len(p.vertices))
+
+	for i, vertex := range p.vertices {
+		vertices[i] = p.center.Add(vertex.Rotate(p.angle))
+	}
+
+	return vertices
+}
+
+// GetBoundingBox returns the bounding box for the polygon.
+func (p *Polygon) GetBoundingBox() (Vector, Vector) {
+	vertices := p.Vertices()
+	min := vertices[0]
+	max := vertices[0]
+
+	for _, vertex := range vertices[1:] {
+		min = NewVector(math.Min(min.X, vertex.X), math.Min(min.Y, vertex.Y))
+		max = NewVector(math.Max(max.X, vertex.X), math.Max(max.Y, vertex.Y))
+	}
+
+	return min, max
+}
+
+// sides returns the edges of the polygon
+// as line segments.
+func (p *Polygon) sides() ([]*Line, error) {
+	vertices := p.Vertices()
+	sides := make([]*Line, 0, len(vertices))
+
+	for i := range vertices {
+		side, err := NewLine(vertices[i],
+			vertices[(i+1)%len(vertices)])
+
+		if err != nil {
+			return nil, err
+		}
+
+		sides = append(sides, side)
+	}
+
+	return sides, nil
+}
+
+// closestPoint returns the point of the polygon
+// outline closest to the given point.
+func (p *Polygon) closestPoint(point Vector) Vector {
+	vertices := p.Vertices()
+	closest := vertices[0]
+	minSquaredDistance := math.Inf(1)
+
+	for i := range vertices {
+		candidate := closestPointOnSegment(vertices[i],
+			vertices[(i+1)%len(vertices)], point)
+		sqrDistance := SquaredDistance(candidate, point)
+
+		if sqrDistance < minSquaredDistance {
+			closest = candidate
+			minSquaredDistance = sqrDistance
+		}
+	}
+
+	return closest
+}
+
+// NewPolygon returns a new convex polygon with the given
+// position, local vertices and angle (in degrees).
+//
+// The vertices may be specified in either clockwise
+// or counter-clockwise order.
+func NewPolygon(position Vector, vertices []Vector, angle float64) (*Polygon, error) {
+	if len(vertices) < 3 {
+		return nil, fmt.Errorf(
+			"the polygon must have at least 3 vertices, but got %d",
+			len(vertices))
+	}
+
+	localVertices := make([]Vector, len(vertices))
+	copy(localVertices, vertices)
+
+	if signedArea(localVertices) < 0 {
+		for i, j := 0, len(localVertices)-1; i < j; i, j = i+1, j-1 {
+			localVertices[i], localVertices[j] = localVertices[j], localVertices[i]
+		}
+	}
+
+	for i := range localVertices {
+		a := localVertices[i]
+		b := localVertices[(i+1)%len(localVertices)]
+		c := localVertices[(i+2)%len(localVertices)]
+
+		if Cross(b.Subtract(a), c.Subtract(b)) < Epsilon {
+			return nil, fmt.Errorf(
+				"the polygon must be convex and have no collinear edges")
+		}
+	}
+
+	polygon := &Polygon{}
+
+	polygon.center = position
+	polygon.vertices = localVertices
+	polygon.Rotate(angle)
+	polygon.treeNodes = []*quadTreeNode{}
+
+	return polygon, nil
+}
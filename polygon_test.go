@@ -0,0 +1,107 @@
+package cirno_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zergon321/cirno"
+)
+
+func TestNewPolygon(t *testing.T) {
+	_, err := cirno.NewPolygon(cirno.Zero(), []cirno.Vector{
+		cirno.NewVector(0, 0), cirno.NewVector(1, 1)}, 0)
+	assert.NotNil(t, err)
+
+	// Concave polygon.
+	_, err = cirno.NewPolygon(cirno.Zero(), []cirno.Vector{
+		cirno.NewVector(0, 0), cirno.NewVector(4, 0),
+		cirno.NewVector(1, 1), cirno.NewVector(0, 4)}, 0)
+	assert.NotNil(t, err)
+
+	// Clockwise vertices must be reordered.
+	polygon, err := cirno.NewPolygon(cirno.NewVector(2, 2), []cirno.Vector{
+		cirno.NewVector(-1, -1), cirno.NewVector(-1, 1),
+		cirno.NewVector(1, 1), cirno.NewVector(1, -1)}, 0)
+	assert.Nil(t, err)
+	assert.True(t, polygon.ContainsPoint(cirno.NewVector(2.5, 1.5)))
+	assert.False(t, polygon.ContainsPoint(cirno.NewVector(3.5, 1.5)))
+
+	polygon.Rotate(45)
+	assert.True(t, polygon.ContainsPoint(cirno.NewVector(2, 3.4)))
+	assert.False(t, polygon.ContainsPoint(cirno.NewVector(2.9, 2.9)))
+}
+
+func TestPolygonCollisions(t *testing.T) {
+	triangle, err := cirno.NewPolygon(cirno.NewVector(0, 0), []cirno.Vector{
+		cirno.NewVector(0, 0), cirno.NewVector(4, 0),
+		cirno.NewVector(0, 4)}, 0)
+	assert.Nil(t, err)
+	c1, err := cirno.NewCircle(cirno.NewVector(3, 3), 1.5)
+	assert.Nil(t, err)
+	c2, err := cirno.NewCircle(cirno.NewVector(3, 3), 0.5)
+	assert.Nil(t, err)
+	r1, err := cirno.NewRectangle(cirno.NewVector(4, 4), 2.5, 2.5, 0)
+	assert.Nil(t, err)
+	r2, err := cirno.NewRectangle(cirno.NewVector(2.5, 2.5), 2, 2, 45)
+	assert.Nil(t, err)
+	l1, err := cirno.NewLine(cirno.NewVector(-1, 2), cirno.NewVector(1, 2))
+	assert.Nil(t, err)
+	l2, err := cirno.NewLine(cirno.NewVector(3, 3), cirno.NewVector(5, 1))
+	assert.Nil(t, err)
+
+	res0, err := cirno.ResolveCollision(triangle, c1, false)
+	assert.Nil(t, err)
+	res1, err := cirno.ResolveCollision(c2, triangle, false)
+	assert.Nil(t, err)
+	res2, err := cirno.ResolveCollision(triangle, r1, false)
+	assert.Nil(t, err)
+	res3, err := cirno.ResolveCollision(r2, triangle, false)
+	assert.Nil(t, err)
+	res4, err := cirno.ResolveCollision(l1, triangle, false)
+	assert.Nil(t, err)
+	res5, err := cirno.ResolveCollision(triangle, l2, false)
+	assert.Nil(t, err)
+
+	assert.True(t, res0)
+	assert.False(t, res1)
+	assert.False(t, res2)
+	assert.True(t, res3)
+	assert.True(t, res4)
+	assert.False(t, res5)
+}
+
+func TestPolygonNormal(t *testing.T) {
+	square, err := cirno.NewPolygon(cirno.NewVector(0, 0), []cirno.Vector{
+		cirno.NewVector(-1, -1), cirno.NewVector(1, -1),
+		cirno.NewVector(1, 1), cirno.NewVector(-1, 1)}, 0)
+	assert.Nil(t, err)
+	rect, err := cirno.NewRectangle(cirno.NewVector(5, 0.5), 2, 2, 0)
+	assert.Nil(t, err)
+
+	normal, err := square.NormalTo(rect)
+	assert.Nil(t, err)
+	assert.True(t, normal.ApproximatelyEqual(cirno.Right()))
+
+	normal, err = rect.NormalTo(square)
+	assert.Nil(t, err)
+	assert.True(t, normal.ApproximatelyEqual(cirno.Left()))
+}
+
+func TestRaycastPolygon(t *testing.T) {
+	polygon, err := cirno.NewPolygon(cirno.NewVector(32, 32), []cirno.Vector{
+		cirno.NewVector(-4, -4), cirno.NewVector(4, -4),
+		cirno.NewVector(0, 4)}, 0)
+	assert.Nil(t, err)
+
+	space, err := cirno.NewSpace(1, 10, 64, 64,
+		cirno.NewVector(0, 0), cirno.NewVector(64, 64), false)
+	assert.Nil(t, err)
+	err = space.Add(polygon)
+	assert.Nil(t, err)
+
+	shape, hit, err := space.Raycast(cirno.NewVector(20, 30),
+		cirno.Right(), 0, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, polygon, shape)
+	assert.InDelta(t, 29, hit.X, 0.0001)
+}
@@ -270,18 +270,273 @@ func (tree *quadTree) clear() error {
 	return nil
 }
 
-// shapeGroups returns the dictionary of shapes grouped
-// by their nodes in the quad tree.
-func (tree *quadTree) shapeGroups() map[*quadTreeNode]Shapes {
-	shapes := map[*quadTreeNode]Shapes{}
+// update moves the shape to the nodes
+// covering its current area.
+func (tree *quadTree) update(shape Shape) error {
+	// Remove the shape from all the nodes that don't contain it
+	// anymore and remove all these nodes from the shape's domain.
+	nodesToRemove := []*quadTreeNode{}
 
-	for node := range tree.leaves {
-		shapes[node] = node.shapes.Copy()
+	for _, node := range shape.nodes() {
+		overlapped, err := node.boundary.collidesShape(shape)
+
+		if err != nil {
+			return err
+		}
+
+		if !overlapped {
+			nodesToRemove = append(nodesToRemove, node)
+		}
+	}
+
+	for _, node := range nodesToRemove {
+		node.shapes.Remove(shape)
+		shape.removeNodes(node)
+	}
+
+	// Add the shape in all the nodes
+	// that must be in its domain.
+	nodeQueue := queue.New()
+	nodeQueue.Enqueue(tree.root)
+
+	for nodeQueue.Len() > 0 {
+		node := nodeQueue.Dequeue().(*quadTreeNode)
+
+		// If the node is already
+		// in the domain, skip it.
+		if shape.containsNode(node) {
+			continue
+		}
+
+		// If the shape is not covered by the node area,
+		// skip it to the next node.
+		overlapped, err := node.boundary.collidesShape(shape)
+
+		if err != nil {
+			return err
+		}
+
+		if !overlapped {
+			continue
+		}
+
+		// If the node is not a leaf,
+		// skip it.
+		if node.northWest != nil {
+			nodeQueue.Enqueue(node.northEast)
+			nodeQueue.Enqueue(node.northWest)
+			nodeQueue.Enqueue(node.southEast)
+			nodeQueue.Enqueue(node.southWest)
+
+			continue
+		}
+
+		// If the node limit is not exceeded,
+		// add the shape in the list of shapes
+		// covered by the node area.
+		if len(node.shapes) < node.tree.nodeCapacity ||
+			node.level >= node.tree.maxLevel {
+			node.shapes.Insert(shape)
+			shape.addNodes(node)
+		} else {
+			// Split the node into four subareas
+			// and add the subnodes in the queue.
+			err := node.split()
+
+			if err != nil {
+				return err
+			}
+
+			nodeQueue.Enqueue(node.northEast)
+			nodeQueue.Enqueue(node.northWest)
+			nodeQueue.Enqueue(node.southEast)
+			nodeQueue.Enqueue(node.southWest)
+		}
+	}
+
+	return nil
+}
+
+// query returns all the shapes of the leaves
+// the overlaps function returns true for.
+func (tree *quadTree) query(overlaps func(boundary *aabb) bool) Shapes {
+	shapes := make(Shapes, 0)
+	nodeQueue := queue.New()
+	nodeQueue.Enqueue(tree.root)
+
+	for nodeQueue.Len() > 0 {
+		node := nodeQueue.Dequeue().(*quadTreeNode)
+
+		if !overlaps(node.boundary) {
+			continue
+		}
+
+		if node.northWest == nil {
+			shapes.Merge(node.shapes)
+
+			continue
+		}
+
+		nodeQueue.Enqueue(node.northEast)
+		nodeQueue.Enqueue(node.northWest)
+		nodeQueue.Enqueue(node.southEast)
+		nodeQueue.Enqueue(node.southWest)
 	}
 
 	return shapes
 }
 
+// Insert inserts the shape into the quad tree.
+func (tree *quadTree) Insert(shape Shape) error {
+	_, err := tree.insert(shape)
+
+	return err
+}
+
+// Remove removes the shape from the quad tree.
+func (tree *quadTree) Remove(shape Shape) error {
+	return tree.remove(shape)
+}
+
+// Update moves the shape to the nodes
+// covering its current area.
+func (tree *quadTree) Update(shape Shape) error {
+	if shape == nil {
+		return fmt.Errorf("the shape is nil")
+	}
+
+	return tree.update(shape)
+}
+
+// QueryAABB returns all the shapes of the
+// leaves overlapping the specified box.
+func (tree *quadTree) QueryAABB(min, max Vector) (Shapes, error) {
+	box := &aabb{min: min, max: max}
+
+	return tree.query(box.overlaps), nil
+}
+
+// QueryRay returns all the shapes of the leaves
+// crossed by the segment from one point to another.
+func (tree *quadTree) QueryRay(from, to Vector) (Shapes, error) {
+	return tree.query(func(boundary *aabb) bool {
+		return boundary.collidesSegment(from, to)
+	}), nil
+}
+
+// Pairs calls the function for every pair
+// of shapes sharing the same leaf.
+func (tree *quadTree) Pairs(fn func(one, other Shape) error) error {
+	buffer := shapeBuffers.Get().(*[]Shape)
+	defer shapeBuffers.Put(buffer)
+
+	for leaf := range tree.leaves {
+		if err := tree.leafPairs(leaf, buffer, fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// leafPairs calls the function for every pair of shapes of the leaf.
+// The pair is reported only by the first of the leaves shared by
+// both the shapes, so no pair is reported twice. The shapes
+// of the leaf are listed in the buffer.
+func (tree *quadTree) leafPairs(leaf *quadTreeNode, buffer *[]Shape, fn func(one, other Shape) error) error {
+	shapes := (*buffer)[:0]
+
+	for shape := range leaf.shapes {
+		shapes = append(shapes, shape)
+	}
+
+	*buffer = shapes
+
+	for i, shape := range shapes {
+		for _, other := range shapes[i+1:] {
+			if shape.sharesNodeBefore(leaf, other) {
+				continue
+			}
+
+			if err := fn(shape, other); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// partitions splits the leaves into the parts
+// and returns the function to find the pairs
+// of shapes within each part.
+func (tree *quadTree) partitions(count int) []func(fn func(one, other Shape) error) error {
+	leaves := make([]*quadTreeNode, 0, len(tree.leaves))
+
+	for leaf := range tree.leaves {
+		leaves = append(leaves, leaf)
+	}
+
+	if count > len(leaves) {
+		count = len(leaves)
+	}
+
+	parts := make([]func(fn func(one, other Shape) error) error, 0, count)
+
+	for i := 0; i < count; i++ {
+		part := leaves[i*len(leaves)/count : (i+1)*len(leaves)/count]
+
+		parts = append(parts, func(fn func(one, other Shape) error) error {
+			buffer := []Shape{}
+
+			for _, leaf := range part {
+				if err := tree.leafPairs(leaf, &buffer, fn); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+	}
+
+	return parts
+}
+
+// Rebuild merges the leaves which
+// don't need to be subdivided anymore.
+func (tree *quadTree) Rebuild() error {
+	return tree.redistribute()
+}
+
+// Clear removes all the shapes from the quad tree.
+func (tree *quadTree) Clear() error {
+	return tree.clear()
+}
+
+// cells returns the boundaries of all
+// the leaves and the shapes within them.
+func (tree *quadTree) cells() map[*aabb]Shapes {
+	cells := map[*aabb]Shapes{}
+
+	for leaf := range tree.leaves {
+		cells[leaf.boundary] = leaf.shapes.Copy()
+	}
+
+	return cells
+}
+
+// shapeCells returns the shapes of all the
+// leaves the shape belongs to by their centers.
+func (tree *quadTree) shapeCells(shape Shape) map[Vector]Shapes {
+	cells := map[Vector]Shapes{}
+
+	for _, node := range shape.nodes() {
+		cells[node.boundary.center()] = node.shapes.Copy()
+	}
+
+	return cells
+}
+
 // newQuadTree creates a new empty quad tree.
 func newQuadTree(boundary *aabb, maxLevel, nodeCapacity int) (*quadTree, error) {
 	if maxLevel < 1 {
@@ -18,6 +18,17 @@ type quadTreeNode struct {
 	level     int
 }
 
+// precedes returns true if the node goes before the other
+// one in the order of their min points, first by Y, then by X.
+// The leaves never overlap, so they're ordered strictly.
+func (node *quadTreeNode) precedes(other *quadTreeNode) bool {
+	if node.boundary.min.Y != other.boundary.min.Y {
+		return node.boundary.min.Y < other.boundary.min.Y
+	}
+
+	return node.boundary.min.X < other.boundary.min.X
+}
+
 // add adds all the shapes covered by node area
 // in the set of node shapes.
 func (node *quadTreeNode) add(shapes Shapes) error {
@@ -2,17 +2,81 @@ package cirno
 
 import (
 	"fmt"
-
-	"github.com/golang-collections/collections/queue"
+	"math"
+	"sort"
 )
 
+// RaycastHit describes the hit of the ray against the shape.
+type RaycastHit struct {
+	// Shape is the shape hit by the ray.
+	Shape Shape
+	// Point is the point where the ray hit the shape.
+	Point Vector
+	// Normal is the normal of the shape
+	// surface at the point of the hit.
+	Normal Vector
+	// Distance is the distance from
+	// the origin of the ray to the point.
+	Distance float64
+	// Fraction is the distance divided
+	// by the length of the ray.
+	Fraction float64
+}
+
 // Raycast casts a ray in the space and returns the hit shape closest
-// to the origin of the ray.
+// to the origin of the ray. In the wrapped space the ray continues
+// on the opposite side after crossing the edge of the space.
 //
 // Ray cannot hit against the shape within which it's located.
 func (space *Space) Raycast(origin, direction Vector, distance float64, mask int32) (Shape, Vector, error) {
+	hits, err := space.raycast(origin, direction, distance, mask, false)
+
+	if err != nil {
+		return nil, Zero(), err
+	}
+
+	if len(hits) <= 0 {
+		return nil, Zero(), nil
+	}
+
+	return hits[0].Shape, hits[0].Point, nil
+}
+
+// RaycastHit casts a ray in the space and returns the hit
+// closest to the origin of the ray. If there's no hit, ok is false.
+//
+// If includeInner is true, the shapes containing the origin of the
+// ray are hit at the origin with the normal opposite to the direction.
+func (space *Space) RaycastHit(origin, direction Vector, distance float64, mask int32, includeInner bool) (hit RaycastHit, ok bool, err error) {
+	hits, err := space.raycast(origin, direction, distance, mask, includeInner)
+
+	if err != nil {
+		return RaycastHit{}, false, err
+	}
+
+	if len(hits) <= 0 {
+		return RaycastHit{}, false, nil
+	}
+
+	return hits[0], true, nil
+}
+
+// RaycastAll casts a ray in the space and returns all
+// the hits sorted by the distance from the origin of the ray.
+// Each hit shape is reported once at the point where
+// the ray enters it.
+//
+// If includeInner is true, the shapes containing the origin of the
+// ray are hit at the origin with the normal opposite to the direction.
+func (space *Space) RaycastAll(origin, direction Vector, distance float64, mask int32, includeInner bool) ([]RaycastHit, error) {
+	return space.raycast(origin, direction, distance, mask, includeInner)
+}
+
+// raycast returns the closest hit for every shape
+// the ray hits sorted by the distance.
+func (space *Space) raycast(origin, direction Vector, distance float64, mask int32, includeInner bool) ([]RaycastHit, error) {
 	if Sign(direction.X) == 0 && Sign(direction.Y) == 0 {
-		return nil, Zero(), fmt.Errorf("the direction vector is Zero()")
+		return nil, fmt.Errorf("the direction vector is Zero()")
 	}
 
 	if distance <= 0 {
@@ -22,117 +86,199 @@ func (space *Space) Raycast(origin, direction Vector, distance float64, mask int
 	normDir, err := direction.Normalize()
 
 	if err != nil {
-		return nil, Zero(), err
+		return nil, err
 	}
 
-	ray, err := NewLine(origin, origin.Add(
+	path, err := NewLine(origin, origin.Add(
 		normDir.MultiplyByScalar(distance)))
 
 	if err != nil {
-		return nil, Zero(), err
+		return nil, err
 	}
 
-	ray.SetMask(mask)
-	nodeQueue := queue.New()
-	nodeQueue.Enqueue(space.tree.root)
-	minExists := false
-
-	var (
-		minSquaredDistance float64
-		hitShape           Shape
-		hit                Vector
-	)
+	// In the wrapped space the ray is also cast from the
+	// positions it would have across the edges of the space.
+	min, max := path.GetBoundingBox()
+	offsets := append([]Vector{Zero()}, space.wrapOffsets(min, max)...)
+	closest := map[Shape]RaycastHit{}
 
-	for nodeQueue.Len() > 0 {
-		node := nodeQueue.Dequeue().(*quadTreeNode)
-		overlapped, err := node.boundary.collidesLine(ray)
+	for _, offset := range offsets {
+		ray, err := NewLine(path.p.Add(offset), path.q.Add(offset))
 
 		if err != nil {
-			return nil, Zero(), err
+			return nil, err
 		}
 
-		if !overlapped {
-			continue
+		ray.SetMask(mask)
+		candidates, err := space.queryRay(ray.p, ray.q)
+
+		if err != nil {
+			return nil, err
 		}
 
-		// If the node is a leaf.
-		if node.northWest == nil {
-			for shape := range node.shapes {
-				raycastHit, err := ResolveCollision(ray, shape, space.useTags)
+		for shape := range candidates {
+			if shape.ContainsPoint(ray.p) {
+				if !includeInner {
+					continue
+				}
+
+				if space.useTags && mask&shape.GetIdentity() <= 0 {
+					continue
+				}
 
-				if err != nil {
-					return nil, Zero(), err
+				closest[shape] = RaycastHit{
+					Shape:  shape,
+					Point:  ray.p,
+					Normal: normDir.MultiplyByScalar(-1),
 				}
 
-				if raycastHit && !shape.ContainsPoint(ray.p) {
-					contacts, err := Contact(ray, shape)
+				continue
+			}
+
+			raycastHit, err := ResolveCollision(ray, shape, space.useTags)
 
-					if err != nil {
-						return nil, Zero(), err
-					}
+			if err != nil {
+				return nil, err
+			}
 
-					for _, contact := range contacts {
-						sqrDistance := SquaredDistance(ray.p, contact)
+			if !raycastHit {
+				continue
+			}
 
-						if !minExists {
-							hit = contact
-							hitShape = shape
-							minSquaredDistance = sqrDistance
+			contacts, err := Contact(ray, shape)
 
-							minExists = true
-						} else if sqrDistance < minSquaredDistance {
-							hit = contact
-							hitShape = shape
-							minSquaredDistance = sqrDistance
-						}
-					}
+			if err != nil {
+				return nil, err
+			}
+
+			if len(contacts) <= 0 {
+				continue
+			}
+
+			hit := contacts[0]
+			minSquaredDistance := SquaredDistance(ray.p, hit)
+
+			for _, contact := range contacts[1:] {
+				if sqrDistance := SquaredDistance(ray.p, contact); sqrDistance < minSquaredDistance {
+					hit = contact
+					minSquaredDistance = sqrDistance
 				}
 			}
-		} else {
-			nodeQueue.Enqueue(node.northEast)
-			nodeQueue.Enqueue(node.northWest)
-			nodeQueue.Enqueue(node.southEast)
-			nodeQueue.Enqueue(node.southWest)
+
+			hitDistance := math.Sqrt(minSquaredDistance)
+
+			if previous, ok := closest[shape]; ok && previous.Distance <= hitDistance {
+				continue
+			}
+
+			closest[shape] = RaycastHit{
+				Shape:    shape,
+				Point:    hit,
+				Normal:   surfaceNormal(shape, hit, normDir),
+				Distance: hitDistance,
+				Fraction: hitDistance / distance,
+			}
 		}
 	}
 
-	return hitShape, hit, nil
+	hits := make([]RaycastHit, 0, len(closest))
+
+	for _, hit := range closest {
+		hits = append(hits, hit)
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].Distance == hits[j].Distance {
+			return space.orderedBefore(hits[i].Shape, hits[j].Shape)
+		}
+
+		return hits[i].Distance < hits[j].Distance
+	})
+
+	return hits, nil
+}
+
+// surfaceNormal returns the normal of the shape surface
+// at the point hit by the ray cast in the direction.
+func surfaceNormal(shape Shape, point, direction Vector) Vector {
+	back := direction.MultiplyByScalar(-1)
+	core, ok := coreOf(shape)
+
+	if !ok {
+		return back
+	}
+
+	var normal Vector
+
+	switch {
+	case core.radius > 0:
+		closest := closestPointOnSegment(core.vertices[0],
+			core.vertices[len(core.vertices)-1], point)
+		radial, err := point.Subtract(closest).Normalize()
+
+		if err != nil {
+			return back
+		}
+
+		// The radial normal of the round
+		// surface always faces outwards.
+		return radial
+
+	case len(core.vertices) > 2:
+		edges := edgesOf(core.vertices)
+		normal = edges[closestEdge(core.vertices, point)].normal
+
+	default:
+		lineNormal, err := core.vertices[1].Subtract(core.vertices[0]).
+			PerpendicularClockwise().Normalize()
+
+		if err != nil {
+			return back
+		}
+
+		normal = lineNormal
+	}
+
+	// The normal should face the ray.
+	if Dot(normal, direction) > 0 {
+		normal = normal.MultiplyByScalar(-1)
+	}
+
+	return normal
 }
 
 // Boxcast casts a box in the space and returns all the
-// shapes overlapped by this box.
+// shapes overlapped by this box. In the wrapped space the part
+// of the box beyond the edge overlaps the opposite side.
 func (space *Space) Boxcast(rect *Rectangle) (Shapes, error) {
 	if rect == nil {
 		return nil, fmt.Errorf("the rectangle is nil")
 	}
 
-	nodeQueue := queue.New()
-	nodeQueue.Enqueue(space.tree.root)
+	images, err := space.imagesOf(rect)
+
+	if err != nil {
+		return nil, err
+	}
+
 	shapes := make(Shapes, 0)
 
-	for nodeQueue.Len() > 0 {
-		node := nodeQueue.Dequeue().(*quadTreeNode)
-		overlapped, err := node.boundary.collidesRectangle(rect)
+	for _, image := range images {
+		candidates, err := space.nearby(image)
 
 		if err != nil {
 			return nil, err
 		}
 
-		if !overlapped {
-			continue
-		}
-
-		if node.northWest == nil {
-			for shape := range node.shapes {
-				boxcastHit, err := ResolveCollision(rect, shape, space.useTags)
+		for shape := range candidates {
+			boxcastHit, err := ResolveCollision(image, shape, space.useTags)
 
-				if err != nil {
-					return nil, err
-				}
+			if err != nil {
+				return nil, err
+			}
 
-				if boxcastHit {
-					shapes.Insert(shape)
-				}
+			if boxcastHit {
+				shapes.Insert(shape)
 			}
 		}
 	}
@@ -141,42 +287,88 @@ func (space *Space) Boxcast(rect *Rectangle) (Shapes, error) {
 }
 
 // Circlecast casts a circle in the space and returns all the
-// shapes overlapped by the circle.
+// shapes overlapped by the circle. In the wrapped space the part
+// of the circle beyond the edge overlaps the opposite side.
 func (space *Space) Circlecast(circle *Circle) (Shapes, error) {
 	if circle == nil {
 		return nil, fmt.Errorf("the circle is nil")
 	}
 
-	nodeQueue := queue.New()
-	nodeQueue.Enqueue(space.tree.root)
+	images, err := space.imagesOf(circle)
+
+	if err != nil {
+		return nil, err
+	}
+
 	shapes := make(Shapes, 0)
 
-	for nodeQueue.Len() > 0 {
-		node := nodeQueue.Dequeue().(*quadTreeNode)
-		overlapped, err := node.boundary.collidesCircle(circle)
+	for _, image := range images {
+		candidates, err := space.nearby(image)
 
 		if err != nil {
 			return nil, err
 		}
 
-		if !overlapped {
-			continue
-		}
-
-		if node.northWest == nil {
-			for shape := range node.shapes {
-				circlecastHit, err := ResolveCollision(circle, shape, space.useTags)
+		for shape := range candidates {
+			circlecastHit, err := ResolveCollision(image, shape, space.useTags)
 
-				if err != nil {
-					return nil, err
-				}
+			if err != nil {
+				return nil, err
+			}
 
-				if circlecastHit {
-					shapes.Insert(shape)
-				}
+			if circlecastHit {
+				shapes.Insert(shape)
 			}
 		}
 	}
 
 	return shapes, nil
 }
+
+// ShapeCast casts the shape in the space along the direction
+// and returns the first shape it would touch on the way.
+// Sensors are ignored. In the wrapped space the shape cast
+// across the edge hits the shapes on the other side.
+//
+// Returns the hit shape (nil if there's none), the fraction of the
+// path before the impact, the point of impact and the normal
+// of the hit shape surface at the point of impact.
+func (space *Space) ShapeCast(shape Shape, direction Vector, distance float64, mask int32) (Shape, float64, Vector, Vector, error) {
+	if shape == nil {
+		return nil, 1, Zero(), Zero(), fmt.Errorf("the shape is nil")
+	}
+
+	if Sign(direction.X) == 0 && Sign(direction.Y) == 0 {
+		return nil, 1, Zero(), Zero(), fmt.Errorf("the direction vector is Zero()")
+	}
+
+	if distance <= 0 {
+		distance = Distance(space.min, space.max)
+	}
+
+	normDir, err := direction.Normalize()
+
+	if err != nil {
+		return nil, 1, Zero(), Zero(), err
+	}
+
+	hitShape, t, normal, point, err := space.sweep(shape,
+		normDir.MultiplyByScalar(distance), 0,
+		func(other Shape) (bool, error) {
+			if !blocks(shape, other) {
+				return false, nil
+			}
+
+			return !space.useTags || mask&other.GetIdentity() > 0, nil
+		})
+
+	if err != nil {
+		return nil, 1, Zero(), Zero(), err
+	}
+
+	if hitShape == nil {
+		return nil, 1, Zero(), Zero(), nil
+	}
+
+	return hitShape, t, point, normal.MultiplyByScalar(-1), nil
+}
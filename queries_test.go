@@ -49,3 +49,94 @@ func TestRaycast(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Nil(t, shape)
 }
+
+func TestShapeCast(t *testing.T) {
+	rect, err := cirno.NewRectangle(cirno.NewVector(40, 32), 4, 20, 0)
+	assert.Nil(t, err)
+	line, err := cirno.NewLine(cirno.NewVector(20, 50), cirno.NewVector(40, 50))
+	assert.Nil(t, err)
+	line.SetIdentity(2)
+
+	space, err := cirno.NewSpace(2, 1, 128, 128,
+		cirno.NewVector(0, 0), cirno.NewVector(64, 64), true)
+	assert.Nil(t, err)
+	err = space.Add(rect, line)
+	assert.Nil(t, err)
+
+	circle, err := cirno.NewCircle(cirno.NewVector(10, 30), 2)
+	assert.Nil(t, err)
+	rect.SetIdentity(1)
+
+	shape, fraction, point, normal, err := space.ShapeCast(circle,
+		cirno.Right(), 40, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, rect, shape)
+	assert.InDelta(t, 0.65, fraction, 0.0001)
+	assert.InDelta(t, 38, point.X, 0.0001)
+	assert.InDelta(t, 30, point.Y, 0.0001)
+	assert.True(t, normal.ApproximatelyEqual(cirno.Left()))
+
+	// The rectangle is filtered out by the mask.
+	shape, _, _, _, err = space.ShapeCast(circle, cirno.Right(), 40, 2)
+	assert.Nil(t, err)
+	assert.Nil(t, shape)
+
+	box, err := cirno.NewRectangle(cirno.NewVector(30, 30), 4, 4, 0)
+	assert.Nil(t, err)
+	shape, fraction, _, normal, err = space.ShapeCast(box,
+		cirno.Up(), 0, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, line, shape)
+	assert.InDelta(t, 18.0/cirno.Distance(space.Min(), space.Max()),
+		fraction, 0.0001)
+	assert.True(t, normal.ApproximatelyEqual(cirno.Down()))
+}
+
+func TestRaycastAll(t *testing.T) {
+	near, err := cirno.NewRectangle(cirno.NewVector(20, 10), 4, 4, 0)
+	assert.Nil(t, err)
+	far, err := cirno.NewCircle(cirno.NewVector(40, 10), 2)
+	assert.Nil(t, err)
+	outer, err := cirno.NewRectangle(cirno.NewVector(10, 10), 6, 6, 0)
+	assert.Nil(t, err)
+
+	space, err := cirno.NewSpace(2, 1, 128, 128,
+		cirno.NewVector(0, 0), cirno.NewVector(64, 64), false)
+	assert.Nil(t, err)
+	err = space.Add(far, near, outer)
+	assert.Nil(t, err)
+
+	hits, err := space.RaycastAll(cirno.NewVector(10, 10),
+		cirno.Right(), 40, 0, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(hits))
+	assert.Equal(t, near, hits[0].Shape)
+	assert.InDelta(t, 18, hits[0].Point.X, 0.0001)
+	assert.InDelta(t, 8, hits[0].Distance, 0.0001)
+	assert.InDelta(t, 0.2, hits[0].Fraction, 0.0001)
+	assert.True(t, hits[0].Normal.ApproximatelyEqual(cirno.Left()))
+	assert.Equal(t, far, hits[1].Shape)
+	assert.InDelta(t, 28, hits[1].Distance, 0.0001)
+	assert.True(t, hits[1].Normal.ApproximatelyEqual(cirno.Left()))
+
+	// The shape containing the origin is hit at the origin.
+	hits, err = space.RaycastAll(cirno.NewVector(10, 10),
+		cirno.Right(), 40, 0, true)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(hits))
+	assert.Equal(t, outer, hits[0].Shape)
+	assert.InDelta(t, 0, hits[0].Distance, 0.0001)
+
+	hit, ok, err := space.RaycastHit(cirno.NewVector(30, 20),
+		cirno.Down(), 20, 0, false)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	hit, ok, err = space.RaycastHit(cirno.NewVector(40, 30),
+		cirno.Down(), 0, 0, false)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, far, hit.Shape)
+	assert.InDelta(t, 12, hit.Point.Y, 0.0001)
+	assert.True(t, hit.Normal.ApproximatelyEqual(cirno.Up()))
+}
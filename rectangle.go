@@ -1,6 +1,9 @@
 package cirno
 
-import "fmt"
+import (
+	"fmt"
+	"math"
+)
 
 // Rectangle represents an oriented euclidian rectangle.
 type Rectangle struct {
@@ -145,6 +148,20 @@ func (r *Rectangle) Vertices() [4]Vector {
 	return [4]Vector{a, b, c, d}
 }
 
+// GetBoundingBox returns the bounding box for the rectangle.
+func (r *Rectangle) GetBoundingBox() (Vector, Vector) {
+	vertices := r.Vertices()
+	min := vertices[0]
+	max := vertices[0]
+
+	for _, vertex := range vertices[1:] {
+		min = NewVector(math.Min(min.X, vertex.X), math.Min(min.Y, vertex.Y))
+		max = NewVector(math.Max(max.X, vertex.X), math.Max(max.Y, vertex.Y))
+	}
+
+	return min, max
+}
+
 // NewRectangle returns a new rectangle with specified parameters.
 func NewRectangle(position Vector, width, height, angle float64) (*Rectangle, error) {
 	if width <= 0.0 {
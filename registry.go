@@ -0,0 +1,239 @@
+package cirno
+
+import "fmt"
+
+// BaseShape contains the tag, the data and the domain
+// of the shape. It should be embedded into user-defined
+// shapes so they can match the Shape interface. The shapes
+// should also implement Cloner to be used in the wrapped
+// spaces and snapshots.
+type BaseShape struct {
+	tag
+	data
+	domain
+}
+
+// CollisionFunc detects if two shapes of
+// the registered types collide.
+type CollisionFunc func(one, other Shape) (bool, error)
+
+// ContactFunc returns the contact points between
+// two shapes of the registered types.
+type ContactFunc func(one, other Shape) ([]Vector, error)
+
+// NormalFunc returns the normal from the first shape
+// to the second one for the registered types.
+type NormalFunc func(one, other Shape) (Vector, error)
+
+var (
+	builtinShapeTypes = map[string]none{
+		"Circle":    {},
+		"Line":      {},
+		"Rectangle": {},
+		"Polygon":   {},
+		"Capsule":   {},
+	}
+	collisionFuncs = map[typePair]CollisionFunc{}
+	contactFuncs   = map[typePair]ContactFunc{}
+	normalFuncs    = map[typePair]NormalFunc{}
+)
+
+// typePair is the registry key
+// for the pair of shape types.
+type typePair struct {
+	one   string
+	other string
+}
+
+// isBuiltin returns true if the shape is of a built-in
+// type. The user-defined shapes are never considered
+// built-in, whatever their type names are.
+func isBuiltin(shape Shape) bool {
+	switch shape.(type) {
+	case *Circle, *Line, *Rectangle, *Polygon, *Capsule:
+		return true
+	}
+
+	return false
+}
+
+// RegisterCollisionFunc registers the function to detect collisions
+// between the shapes of the specified types. The function is also
+// used for the reversed pair of types unless it's registered as well.
+//
+// The built-in functions are used only for the shapes of the
+// built-in Go types, so the user-defined shapes always go
+// through the registered ones, whatever their type names are.
+//
+// The quad tree puts the user-defined shapes into its nodes using
+// the function registered for Rectangle and the type. If there's
+// no such function, the bounding boxes of the shapes are used.
+//
+// Registration is not thread-safe and should be done
+// before the shapes of the types are used.
+func RegisterCollisionFunc(oneType, otherType string, fn CollisionFunc) error {
+	id, err := registryKey(oneType, otherType)
+
+	if err != nil {
+		return err
+	}
+
+	if fn == nil {
+		return fmt.Errorf("the collision function is nil")
+	}
+
+	collisionFuncs[id] = fn
+
+	return nil
+}
+
+// RegisterContactFunc registers the function to find contacts
+// between the shapes of the specified types. The function is also
+// used for the reversed pair of types unless it's registered as well.
+//
+// Registration is not thread-safe and should be done
+// before the shapes of the types are used.
+func RegisterContactFunc(oneType, otherType string, fn ContactFunc) error {
+	id, err := registryKey(oneType, otherType)
+
+	if err != nil {
+		return err
+	}
+
+	if fn == nil {
+		return fmt.Errorf("the contact function is nil")
+	}
+
+	contactFuncs[id] = fn
+
+	return nil
+}
+
+// RegisterNormalFunc registers the function to compute the normal
+// between the shapes of the specified types. The function is also
+// used for the reversed pair of types with the normal inverted
+// unless it's registered as well.
+//
+// Registration is not thread-safe and should be done
+// before the shapes of the types are used.
+func RegisterNormalFunc(oneType, otherType string, fn NormalFunc) error {
+	id, err := registryKey(oneType, otherType)
+
+	if err != nil {
+		return err
+	}
+
+	if fn == nil {
+		return fmt.Errorf("the normal function is nil")
+	}
+
+	normalFuncs[id] = fn
+
+	return nil
+}
+
+// NormalBetween returns the normal from the first shape
+// to the second one using either the built-in methods
+// or the registered normal functions.
+//
+// User-defined shapes may call it in their NormalTo methods.
+func NormalBetween(one, other Shape) (Vector, error) {
+	if one == nil {
+		return Zero(), fmt.Errorf("the first shape is nil")
+	}
+
+	if other == nil {
+		return Zero(), fmt.Errorf("the second shape is nil")
+	}
+
+	if isBuiltin(one) && isBuiltin(other) {
+		return one.NormalTo(other)
+	}
+
+	return registeredNormal(one, other)
+}
+
+// registryKey returns the registry key for
+// the pair of the shape types.
+func registryKey(oneType, otherType string) (typePair, error) {
+	if oneType == "" || otherType == "" {
+		return typePair{}, fmt.Errorf("the shape type name is empty")
+	}
+
+	_, oneBuiltin := builtinShapeTypes[oneType]
+	_, otherBuiltin := builtinShapeTypes[otherType]
+
+	if oneBuiltin && otherBuiltin {
+		return typePair{}, fmt.Errorf(
+			"the combination of '%s' and '%s' is built-in",
+			oneType, otherType)
+	}
+
+	return typePair{one: oneType, other: otherType}, nil
+}
+
+// isCollisionRegistered returns true if the collision function
+// is registered for the pair of types in any order.
+func isCollisionRegistered(oneType, otherType string) bool {
+	if _, ok := collisionFuncs[typePair{oneType, otherType}]; ok {
+		return true
+	}
+
+	_, ok := collisionFuncs[typePair{otherType, oneType}]
+
+	return ok
+}
+
+// registeredCollision detects if two shapes collide
+// using the registered collision function.
+func registeredCollision(one, other Shape) (bool, error) {
+	if fn, ok := collisionFuncs[typePair{one.TypeName(), other.TypeName()}]; ok {
+		return fn(one, other)
+	}
+
+	if fn, ok := collisionFuncs[typePair{other.TypeName(), one.TypeName()}]; ok {
+		return fn(other, one)
+	}
+
+	return false, fmt.Errorf(
+		"unknown shape type combination: '%s' and '%s'",
+		one.TypeName(), other.TypeName())
+}
+
+// registeredContact returns the contacts between two shapes
+// using the registered contact function.
+func registeredContact(one, other Shape) ([]Vector, error) {
+	if fn, ok := contactFuncs[typePair{one.TypeName(), other.TypeName()}]; ok {
+		return fn(one, other)
+	}
+
+	if fn, ok := contactFuncs[typePair{other.TypeName(), one.TypeName()}]; ok {
+		return fn(other, one)
+	}
+
+	return nil, fmt.Errorf(
+		"unknown shape type combination: '%s' and '%s'",
+		one.TypeName(), other.TypeName())
+}
+
+// registeredNormal returns the normal between two shapes
+// using the registered normal function.
+func registeredNormal(one, other Shape) (Vector, error) {
+	if fn, ok := normalFuncs[typePair{one.TypeName(), other.TypeName()}]; ok {
+		return fn(one, other)
+	}
+
+	if fn, ok := normalFuncs[typePair{other.TypeName(), one.TypeName()}]; ok {
+		normal, err := fn(other, one)
+
+		if err != nil {
+			return Zero(), err
+		}
+
+		return normal.MultiplyByScalar(-1), nil
+	}
+
+	return Zero(), fmt.Errorf(
+		"unknown shape type combination: '%s' and '%s'",
+		one.TypeName(), other.TypeName())
+}
@@ -0,0 +1,323 @@
+package cirno_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zergon321/cirno"
+)
+
+// point is a user-defined shape
+// occupying a single point.
+type point struct {
+	position cirno.Vector
+	cirno.BaseShape
+}
+
+func (p *point) TypeName() string                      { return "Point" }
+func (p *point) Center() cirno.Vector                  { return p.position }
+func (p *point) Angle() float64                        { return 0 }
+func (p *point) AngleRadians() float64                 { return 0 }
+func (p *point) Rotate(float64) float64                { return 0 }
+func (p *point) RotateRadians(float64) float64         { return 0 }
+func (p *point) SetAngle(float64) float64              { return 0 }
+func (p *point) SetAngleRadians(float64) float64       { return 0 }
+func (p *point) ContainsPoint(other cirno.Vector) bool { return p.position.ApproximatelyEqual(other) }
+
+func (p *point) GetBoundingBox() (cirno.Vector, cirno.Vector) {
+	return p.position, p.position
+}
+
+func (p *point) Move(direction cirno.Vector) cirno.Vector {
+	p.position = p.position.Add(direction)
+
+	return p.position
+}
+
+func (p *point) SetPosition(pos cirno.Vector) cirno.Vector {
+	p.position = pos
+
+	return p.position
+}
+
+func (p *point) RotateAround(angle float64, base cirno.Vector) cirno.Vector {
+	p.position = p.position.RotateAround(angle, base)
+
+	return p.position
+}
+
+func (p *point) RotateAroundRadians(angle float64, base cirno.Vector) cirno.Vector {
+	p.position = p.position.RotateAroundRadians(angle, base)
+
+	return p.position
+}
+
+func (p *point) NormalTo(other cirno.Shape) (cirno.Vector, error) {
+	return cirno.NormalBetween(p, other)
+}
+
+func init() {
+	err := cirno.RegisterCollisionFunc("Point", "Circle",
+		func(one, other cirno.Shape) (bool, error) {
+			return other.ContainsPoint(one.Center()), nil
+		})
+
+	if err != nil {
+		panic(err)
+	}
+
+	err = cirno.RegisterCollisionFunc("Rectangle", "Point",
+		func(one, other cirno.Shape) (bool, error) {
+			return one.ContainsPoint(other.Center()), nil
+		})
+
+	if err != nil {
+		panic(err)
+	}
+
+	err = cirno.RegisterNormalFunc("Point", "Circle",
+		func(one, other cirno.Shape) (cirno.Vector, error) {
+			return other.Center().Subtract(one.Center()).Normalize()
+		})
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+func TestRegisterBuiltinPair(t *testing.T) {
+	err := cirno.RegisterCollisionFunc("Circle", "Line",
+		func(one, other cirno.Shape) (bool, error) {
+			return true, nil
+		})
+	assert.NotNil(t, err)
+}
+
+func TestUserDefinedShape(t *testing.T) {
+	p := &point{position: cirno.NewVector(4, 4)}
+	circle, err := cirno.NewCircle(cirno.NewVector(5, 4), 2)
+	assert.Nil(t, err)
+	farCircle, err := cirno.NewCircle(cirno.NewVector(-5, -5), 2)
+	assert.Nil(t, err)
+
+	res0, err := cirno.ResolveCollision(p, circle, false)
+	assert.Nil(t, err)
+	res1, err := cirno.ResolveCollision(farCircle, p, false)
+	assert.Nil(t, err)
+	assert.True(t, res0)
+	assert.False(t, res1)
+
+	normal, err := p.NormalTo(circle)
+	assert.Nil(t, err)
+	assert.True(t, normal.ApproximatelyEqual(cirno.Right()))
+	normal, err = circle.NormalTo(p)
+	assert.Nil(t, err)
+	assert.True(t, normal.ApproximatelyEqual(cirno.Left()))
+
+	_, err = cirno.Contact(p, circle)
+	assert.NotNil(t, err)
+
+	space, err := cirno.NewSpace(2, 1, 20, 20,
+		cirno.NewVector(-10, -10), cirno.NewVector(10, 10), false)
+	assert.Nil(t, err)
+	err = space.Add(p, circle, farCircle)
+	assert.Nil(t, err)
+
+	shapes, err := space.CollidingWith(p)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(shapes))
+	res2, err := shapes.Contains(circle)
+	assert.Nil(t, err)
+	assert.True(t, res2)
+}
+
+// dot is a user-defined point shape
+// with no Rectangle collision function.
+type dot struct {
+	point
+}
+
+func (d *dot) TypeName() string { return "Dot" }
+
+func init() {
+	err := cirno.RegisterCollisionFunc("Dot", "Circle",
+		func(one, other cirno.Shape) (bool, error) {
+			return other.ContainsPoint(one.Center()), nil
+		})
+
+	if err != nil {
+		panic(err)
+	}
+
+	err = cirno.RegisterContactFunc("Dot", "Circle",
+		func(one, other cirno.Shape) ([]cirno.Vector, error) {
+			return []cirno.Vector{one.Center()}, nil
+		})
+
+	if err != nil {
+		panic(err)
+	}
+
+	err = cirno.RegisterPenetrationFunc("Dot", "Circle",
+		func(one, other cirno.Shape) (cirno.Vector, float64, bool, error) {
+			circle := other.(*cirno.Circle)
+			offset := other.Center().Subtract(one.Center())
+			normal, err := offset.Normalize()
+
+			if err != nil {
+				return cirno.Zero(), 0, false, err
+			}
+
+			depth := circle.Radius() - offset.Magnitude()
+
+			return normal, depth, depth > 0, nil
+		})
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+func TestUserDefinedShapeWithoutRectangleFunc(t *testing.T) {
+	d := &dot{point{position: cirno.NewVector(4, 4)}}
+	circle, err := cirno.NewCircle(cirno.NewVector(5, 4), 2)
+	assert.Nil(t, err)
+
+	space, err := cirno.NewSpace(2, 1, 20, 20,
+		cirno.NewVector(-10, -10), cirno.NewVector(10, 10), false)
+	assert.Nil(t, err)
+	err = space.Add(d, circle)
+	assert.Nil(t, err)
+
+	d.Move(cirno.NewVector(-8, -8))
+	_, err = space.Update(d)
+	assert.Nil(t, err)
+
+	shapes, err := space.CollidedBy(d)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(shapes))
+
+	d.Move(cirno.NewVector(8, 8))
+	_, err = space.Update(d)
+	assert.Nil(t, err)
+
+	shapes, err = space.CollidedBy(d)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(shapes))
+}
+
+// watched is a user-defined point shape
+// which counts the changes of its transform.
+type watched struct {
+	point
+	changes int
+}
+
+func (w *watched) Move(direction cirno.Vector) cirno.Vector {
+	w.changes++
+
+	return w.point.Move(direction)
+}
+
+func (w *watched) SetPosition(pos cirno.Vector) cirno.Vector {
+	w.changes++
+
+	return w.point.SetPosition(pos)
+}
+
+func (w *watched) SetAngle(angle float64) float64 {
+	w.changes++
+
+	return w.point.SetAngle(angle)
+}
+
+func TestUserDefinedShapePredictiveQueries(t *testing.T) {
+	p := &watched{point: point{position: cirno.NewVector(-5, 4)}}
+	circle, err := cirno.NewCircle(cirno.NewVector(5, 4), 2)
+	assert.Nil(t, err)
+
+	space, err := cirno.NewSpace(2, 1, 20, 20,
+		cirno.NewVector(-10, -10), cirno.NewVector(10, 10), false)
+	assert.Nil(t, err)
+	err = space.Add(p, circle)
+	assert.Nil(t, err)
+
+	movement := cirno.NewVector(10, 0)
+
+	shapes, err := space.WouldBeCollidedBy(p, movement, 0)
+	assert.Nil(t, err)
+	assert.Len(t, shapes, 1)
+	assert.Contains(t, shapes, circle)
+
+	shapes, err = space.WouldBeCollidingWith(p, movement, 90)
+	assert.Nil(t, err)
+	assert.Len(t, shapes, 1)
+
+	shapes, err = space.WouldBeCollidedBy(p, cirno.NewVector(0, 5), 0)
+	assert.Nil(t, err)
+	assert.Len(t, shapes, 0)
+
+	pos, _, shape, err := cirno.Approximate(p, movement, 0,
+		cirno.Shapes{circle: {}}, 10, false)
+	assert.Nil(t, err)
+	assert.Equal(t, circle, shape)
+	assert.True(t, pos.X < 3)
+
+	// The shape which can't be copied is never touched.
+	assert.Equal(t, 0, p.changes)
+	assert.Equal(t, cirno.NewVector(-5, 4), p.Center())
+
+	shapes, err = space.CollidedBy(p)
+	assert.Nil(t, err)
+	assert.Len(t, shapes, 0)
+}
+
+// named is a user-defined point shape
+// with the given type name.
+type named struct {
+	point
+	name string
+}
+
+func (n *named) TypeName() string { return n.name }
+
+func TestUserDefinedShapeWithBuiltinName(t *testing.T) {
+	impostor := &named{point{position: cirno.NewVector(5, 4)}, "Circle"}
+	circle, err := cirno.NewCircle(cirno.NewVector(5, 4), 2)
+	assert.Nil(t, err)
+
+	// The shape isn't treated as a built-in one.
+	_, err = cirno.ResolveCollision(impostor, circle, false)
+	assert.NotNil(t, err)
+	_, err = cirno.Contact(circle, impostor)
+	assert.NotNil(t, err)
+	_, err = cirno.NormalBetween(impostor, circle)
+	assert.NotNil(t, err)
+	_, _, _, err = cirno.Penetration(circle, impostor)
+	assert.NotNil(t, err)
+}
+
+func TestRegistryTypePairs(t *testing.T) {
+	err := cirno.RegisterCollisionFunc("a_b", "c",
+		func(one, other cirno.Shape) (bool, error) {
+			return true, nil
+		})
+	assert.Nil(t, err)
+	err = cirno.RegisterCollisionFunc("a", "b_c",
+		func(one, other cirno.Shape) (bool, error) {
+			return false, nil
+		})
+	assert.Nil(t, err)
+
+	ab := &named{name: "a_b"}
+	c := &named{name: "c"}
+	a := &named{name: "a"}
+	bc := &named{name: "b_c"}
+
+	overlapped, err := cirno.ResolveCollision(ab, c, false)
+	assert.Nil(t, err)
+	assert.True(t, overlapped)
+	overlapped, err = cirno.ResolveCollision(a, bc, false)
+	assert.Nil(t, err)
+	assert.False(t, overlapped)
+}
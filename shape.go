@@ -1,6 +1,9 @@
 package cirno
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+)
 
 // Shape represents a shape in the space.
 type Shape interface {
@@ -19,6 +22,7 @@ type Shape interface {
 	SetAngleRadians(float64) float64
 	ContainsPoint(Vector) bool
 	NormalTo(Shape) (Vector, error)
+	GetBoundingBox() (Vector, Vector)
 
 	// Tag-related methods.
 	GetIdentity() int32
@@ -26,17 +30,22 @@ type Shape interface {
 	GetMask() int32
 	SetMask(int32)
 	ShouldCollide(Shape) (bool, error)
+	IsSensor() bool
+	SetSensor(bool)
 
 	// Data-related methods.
 	Data() interface{}
 	SetData(data interface{})
 
 	// Domain-related methods.
+	ID() uint64
 	nodes() []*quadTreeNode
 	addNodes(...*quadTreeNode)
 	containsNode(*quadTreeNode) bool
+	sharesNodeBefore(*quadTreeNode, Shape) bool
 	removeNodes(...*quadTreeNode)
 	clearNodes()
+	setID(uint64)
 }
 
 // Shapes represents a list of shapes.
@@ -104,6 +113,64 @@ func (shapes Shapes) Items() []Shape {
 	return items
 }
 
+// OrderedItems returns the list of all the shapes
+// from the set sorted by their IDs, so in the order
+// of their insertion in the space. The shapes with
+// the same ID, for example the ones never added to
+// a space, are ordered by placedBefore: by their types,
+// positions, angles, bounding boxes and tags.
+//
+// The shapes which are equal in all of that are left
+// in no particular order, so their order may vary
+// between runs.
+//
+// The shapes added to several spaces have the IDs assigned
+// by the last of them, Space.Ordered should be used
+// to order them within the other spaces.
+func (shapes Shapes) OrderedItems() []Shape {
+	items := shapes.Items()
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].ID() != items[j].ID() {
+			return items[i].ID() < items[j].ID()
+		}
+
+		return placedBefore(items[i], items[j])
+	})
+
+	return items
+}
+
+// placedBefore compares the shapes by their type names,
+// positions, angles, bounding boxes and tags.
+func placedBefore(one, other Shape) bool {
+	if one.TypeName() != other.TypeName() {
+		return one.TypeName() < other.TypeName()
+	}
+
+	oneMin, oneMax := one.GetBoundingBox()
+	otherMin, otherMax := other.GetBoundingBox()
+	oneKeys := [...]float64{one.Center().X, one.Center().Y, one.Angle(),
+		oneMin.X, oneMin.Y, oneMax.X, oneMax.Y}
+	otherKeys := [...]float64{other.Center().X, other.Center().Y, other.Angle(),
+		otherMin.X, otherMin.Y, otherMax.X, otherMax.Y}
+
+	for i := range oneKeys {
+		if oneKeys[i] != otherKeys[i] {
+			return oneKeys[i] < otherKeys[i]
+		}
+	}
+
+	if one.GetIdentity() != other.GetIdentity() {
+		return one.GetIdentity() < other.GetIdentity()
+	}
+
+	if one.GetMask() != other.GetMask() {
+		return one.GetMask() < other.GetMask()
+	}
+
+	return !one.IsSensor() && other.IsSensor()
+}
+
 // Copy returns a new hash set with same shapes.
 func (shapes Shapes) Copy() Shapes {
 	setCopy := make(Shapes, len(shapes))
@@ -0,0 +1,182 @@
+package cirno
+
+import "fmt"
+
+// WithSleeping makes the shapes fall asleep when they haven't
+// been updated for the specified number of steps. The pairs of
+// sleeping shapes are not checked for collision. The sleeping
+// shape is woken up when it's updated, when another shape is added
+// to or moves into any of its quad tree nodes, or when the bounding
+// box of the added or updated shape overlaps the bounding box
+// of the sleeping shape.
+//
+// The steps are counted only by Step,
+// CollidingShapes doesn't count them.
+//
+// The static shapes are considered always sleeping,
+// so they're checked only against the awake shapes.
+func WithSleeping(steps int) SpaceOption {
+	return func(space *Space) error {
+		if steps <= 0 {
+			return fmt.Errorf("the number of steps must be positive")
+		}
+
+		space.sleepSteps = steps
+
+		return nil
+	}
+}
+
+// IsSleeping returns true if the shape is sleeping.
+func (space *Space) IsSleeping(shape Shape) (bool, error) {
+	if shape == nil {
+		return false, fmt.Errorf("the shape is nil")
+	}
+
+	if _, ok := space.shapes[shape]; !ok {
+		return false, fmt.Errorf(
+			"the space doesn't contain the given shape")
+	}
+
+	return !space.awake(shape), nil
+}
+
+// Wake wakes up the shape, so it's checked for
+// collision until it falls asleep again.
+func (space *Space) Wake(shape Shape) error {
+	if shape == nil {
+		return fmt.Errorf("the shape is nil")
+	}
+
+	if _, ok := space.shapes[shape]; !ok {
+		return fmt.Errorf(
+			"the space doesn't contain the given shape")
+	}
+
+	space.wake(shape)
+
+	return nil
+}
+
+// awake returns true if the shape should be
+// checked for collision against the other
+// sleeping shapes.
+func (space *Space) awake(shape Shape) bool {
+	if space.sleepSteps <= 0 {
+		return true
+	}
+
+	if space.kinds[shape] == BodyStatic {
+		return false
+	}
+
+	_, sleeping := space.sleeping[shape]
+
+	return !sleeping
+}
+
+// wake wakes up the shape and
+// resets its idle steps counter.
+func (space *Space) wake(shape Shape) {
+	delete(space.sleeping, shape)
+	delete(space.idleSteps, shape)
+}
+
+// wakeEntered wakes up the shape, the shapes of the quad tree
+// nodes the shape has entered since it was in the previous
+// nodes and the shapes whose bounding boxes overlap
+// the bounding box of the shape.
+func (space *Space) wakeEntered(shape Shape, previous []*quadTreeNode) error {
+	if space.sleepSteps <= 0 {
+		return nil
+	}
+
+	space.wake(shape)
+
+	for _, node := range shape.nodes() {
+		if Broadphase(node.tree) != space.index ||
+			containsNode(previous, node) {
+			continue
+		}
+
+		for other := range node.shapes {
+			space.wake(other)
+		}
+	}
+
+	min, max := shape.GetBoundingBox()
+	box := &aabb{min: min, max: max}
+	nearby, err := space.queryAABB(min, max)
+
+	if err != nil {
+		return err
+	}
+
+	for other := range nearby {
+		if boundingBoxOf(other).overlaps(box) {
+			space.wake(other)
+		}
+	}
+
+	return nil
+}
+
+// containsNode returns true if the
+// list contains the quad tree node.
+func containsNode(nodes []*quadTreeNode, node *quadTreeNode) bool {
+	for _, item := range nodes {
+		if item == node {
+			return true
+		}
+	}
+
+	return false
+}
+
+// advanceSleep counts the step for all the awake shapes
+// and puts to sleep the ones idle for too long.
+func (space *Space) advanceSleep() {
+	if space.sleepSteps <= 0 {
+		return
+	}
+
+	for shape := range space.shapes {
+		if !space.awake(shape) {
+			continue
+		}
+
+		space.idleSteps[shape]++
+
+		if space.idleSteps[shape] >= space.sleepSteps {
+			space.sleeping.Insert(shape)
+			delete(space.idleSteps, shape)
+		}
+	}
+}
+
+// keepSleepingContacts adds the contacts of the previous
+// step between the sleeping shapes to the colliding shapes,
+// so they don't stop colliding when falling asleep.
+func (space *Space) keepSleepingContacts(collidingShapes map[Shape]Shapes) {
+	if space.sleepSteps <= 0 {
+		return
+	}
+
+	for shape, others := range space.contacts {
+		if _, ok := space.shapes[shape]; !ok || space.awake(shape) {
+			continue
+		}
+
+		for other := range others {
+			if _, ok := space.shapes[other]; !ok || space.awake(other) {
+				continue
+			}
+
+			if _, ok := collidingShapes[shape]; !ok {
+				collidingShapes[shape] = make(Shapes, 0)
+			}
+
+			collidingShapes[shape].Insert(other)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package cirno_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zergon321/cirno"
+)
+
+func TestSleeping(t *testing.T) {
+	_, err := cirno.NewSpace(2, 2, 40, 40, cirno.NewVector(-20, -20),
+		cirno.NewVector(20, 20), false, cirno.WithSleeping(0))
+	assert.NotNil(t, err)
+
+	space, err := cirno.NewSpace(2, 2, 40, 40, cirno.NewVector(-20, -20),
+		cirno.NewVector(20, 20), false, cirno.WithSleeping(2))
+	assert.Nil(t, err)
+
+	crate, err := cirno.NewCircle(cirno.NewVector(5, 5), 1)
+	assert.Nil(t, err)
+	debris, err := cirno.NewCircle(cirno.NewVector(6, 5), 1)
+	assert.Nil(t, err)
+	player, err := cirno.NewCircle(cirno.NewVector(-15, -15), 1)
+	assert.Nil(t, err)
+	err = space.Add(crate, debris, player)
+	assert.Nil(t, err)
+
+	ended := 0
+	err = space.HandleShape(crate, cirno.CollisionHandler{
+		End: func(shape, other cirno.Shape) { ended++ },
+	})
+	assert.Nil(t, err)
+
+	for i := 0; i < 2; i++ {
+		_, err = space.Step()
+		assert.Nil(t, err)
+	}
+
+	sleeping, err := space.IsSleeping(crate)
+	assert.Nil(t, err)
+	assert.True(t, sleeping)
+
+	// The pairs of the sleeping shapes are skipped,
+	// but their contacts are kept.
+	shapes, err := space.CollidingShapes()
+	assert.Nil(t, err)
+	assert.Empty(t, shapes)
+	shapes, err = space.Step()
+	assert.Nil(t, err)
+	assert.Contains(t, shapes[crate], debris)
+	assert.Equal(t, 0, ended)
+
+	// The player moving into the cell
+	// of the crate wakes it up.
+	player.SetPosition(cirno.NewVector(8.5, 8.5))
+	_, err = space.Update(player)
+	assert.Nil(t, err)
+	sleeping, err = space.IsSleeping(crate)
+	assert.Nil(t, err)
+	assert.False(t, sleeping)
+
+	shapes, err = space.CollidingShapes()
+	assert.Nil(t, err)
+	assert.Contains(t, shapes[crate], debris)
+
+	err = space.Wake(debris)
+	assert.Nil(t, err)
+	sleeping, err = space.IsSleeping(debris)
+	assert.Nil(t, err)
+	assert.False(t, sleeping)
+}
+
+func TestSleepingNeighbours(t *testing.T) {
+	space, err := cirno.NewSpace(2, 4, 40, 40, cirno.NewVector(-20, -20),
+		cirno.NewVector(20, 20), false, cirno.WithSleeping(1))
+	assert.Nil(t, err)
+
+	crate, err := cirno.NewCircle(cirno.NewVector(5, 5), 1)
+	assert.Nil(t, err)
+	player, err := cirno.NewCircle(cirno.NewVector(12, 12), 1)
+	assert.Nil(t, err)
+	err = space.Add(crate, player)
+	assert.Nil(t, err)
+
+	// CollidingShapes doesn't count the steps.
+	_, err = space.CollidingShapes()
+	assert.Nil(t, err)
+	sleeping, err := space.IsSleeping(crate)
+	assert.Nil(t, err)
+	assert.False(t, sleeping)
+
+	_, err = space.Step()
+	assert.Nil(t, err)
+
+	// The player moving within the node of the crate
+	// doesn't wake it up until they touch.
+	for i := 0; i < 3; i++ {
+		player.Move(cirno.NewVector(-1, -1))
+		_, err = space.Update(player)
+		assert.Nil(t, err)
+		_, err = space.Step()
+		assert.Nil(t, err)
+
+		sleeping, err = space.IsSleeping(crate)
+		assert.Nil(t, err)
+		assert.True(t, sleeping)
+	}
+
+	player.Move(cirno.NewVector(-3, -3))
+	_, err = space.Update(player)
+	assert.Nil(t, err)
+	sleeping, err = space.IsSleeping(crate)
+	assert.Nil(t, err)
+	assert.False(t, sleeping)
+}
@@ -0,0 +1,364 @@
+package cirno
+
+import (
+	"fmt"
+	"sort"
+)
+
+// shapeState is the state of the
+// shape saved in the snapshot.
+type shapeState struct {
+	shape    Shape
+	geometry Shape
+	id       uint64
+	kind     BodyKind
+	identity int32
+	mask     int32
+	sensor   bool
+	sleeping bool
+	idle     int
+}
+
+// Snapshot is the immutable state of the space: the shapes
+// within it, their transforms, tags, kinds and IDs, the bounds,
+// the area covered by the broadphases, the sleep state, the
+// contacts used by the events and the handlers of the shapes.
+//
+// The states of the shapes which haven't changed since the
+// previous snapshot are shared, so the snapshot is cheap
+// to take every step.
+type Snapshot struct {
+	space      *Space
+	states     []*shapeState
+	nextID     uint64
+	min        Vector
+	max        Vector
+	indexArea  *coveredArea
+	staticArea *coveredArea
+	contacts   map[Shape]Shapes
+	handlers   map[Shape]CollisionHandler
+}
+
+// Len returns the number of the shapes in the snapshot.
+func (snapshot *Snapshot) Len() int {
+	return len(snapshot.states)
+}
+
+// Snapshot saves the current state of the space.
+//
+// Only the states of the shapes changed since the previous
+// snapshot are created. The states are kept in the order
+// of the IDs: the shapes added since the previous snapshot
+// have greater IDs, so only they are sorted.
+//
+// The user-defined shapes must implement Cloner. Only their
+// position and angle are restored, the built-in shapes are
+// restored exactly.
+func (space *Space) Snapshot() (*Snapshot, error) {
+	if space.saved == nil {
+		space.saved = make(map[Shape]*shapeState, len(space.shapes))
+	}
+
+	states := make([]*shapeState, 0, len(space.shapes))
+
+	for _, previous := range space.savedStates {
+		shape := previous.shape
+
+		// Skip the shapes removed since the previous
+		// snapshot and the ones added again.
+		if id, ok := space.ids[shape]; !ok || id != previous.id {
+			if space.saved[shape] == previous {
+				delete(space.saved, shape)
+			}
+
+			continue
+		}
+
+		state, err := space.stateOf(shape, previous)
+
+		if err != nil {
+			return nil, err
+		}
+
+		states = append(states, state)
+		space.saved[shape] = state
+	}
+
+	added := []Shape{}
+
+	for shape := range space.shapes {
+		if state, ok := space.saved[shape]; !ok ||
+			state.id != space.ids[shape] {
+			added = append(added, shape)
+		}
+	}
+
+	sort.Slice(added, func(i, j int) bool {
+		return space.ids[added[i]] < space.ids[added[j]]
+	})
+
+	for _, shape := range added {
+		state, err := space.stateOf(shape, nil)
+
+		if err != nil {
+			return nil, err
+		}
+
+		states = append(states, state)
+		space.saved[shape] = state
+	}
+
+	space.savedStates = states
+
+	// The contacts are never changed after the step, so they're
+	// shared. The handlers are copied once they're changed.
+	space.handlersShared = true
+
+	return &Snapshot{
+		space:      space,
+		states:     states,
+		nextID:     space.nextID,
+		min:        space.min,
+		max:        space.max,
+		indexArea:  areaOf(space.index),
+		staticArea: areaOf(space.staticIndex),
+		contacts:   space.contacts,
+		handlers:   space.shapeHandlers,
+	}, nil
+}
+
+// stateOf returns the current state of the shape.
+// The previous state is returned if the shape
+// hasn't changed since it was saved.
+func (space *Space) stateOf(shape Shape, previous *shapeState) (*shapeState, error) {
+	_, sleeping := space.sleeping[shape]
+	state := shapeState{
+		shape:    shape,
+		id:       space.ids[shape],
+		kind:     space.kinds[shape],
+		identity: shape.GetIdentity(),
+		mask:     shape.GetMask(),
+		sensor:   shape.IsSensor(),
+		sleeping: sleeping,
+		idle:     space.idleSteps[shape],
+	}
+
+	if previous != nil && sameGeometry(previous.geometry, shape) {
+		state.geometry = previous.geometry
+
+		if state == *previous {
+			return previous, nil
+		}
+	}
+
+	if state.geometry == nil {
+		geometry, err := cloneShape(shape)
+
+		if err != nil {
+			return nil, err
+		}
+
+		state.geometry = geometry
+	}
+
+	changed := new(shapeState)
+	*changed = state
+
+	return changed, nil
+}
+
+// areaOf returns the area covered by the
+// broadphase or nil if it's not growable.
+func areaOf(index Broadphase) *coveredArea {
+	growable, ok := index.(growableBroadphase)
+
+	if !ok {
+		return nil
+	}
+
+	area := growable.area()
+
+	return &area
+}
+
+// Restore brings the space back to the state saved in the snapshot.
+// The shapes added after the snapshot was taken are removed, and
+// the removed ones are added back. The indices are rebuilt with
+// the area they covered when the snapshot was taken by inserting
+// the shapes in the order of their IDs, so the result doesn't
+// depend on what happened after the snapshot.
+//
+// The IDs assigned after the snapshot was taken are assigned again.
+func (space *Space) Restore(snapshot *Snapshot) error {
+	if snapshot == nil {
+		return fmt.Errorf("the snapshot is nil")
+	}
+
+	if snapshot.space != space {
+		return fmt.Errorf("the snapshot was taken from another space")
+	}
+
+	if err := restoreArea(space.index, snapshot.indexArea); err != nil {
+		return err
+	}
+
+	if err := restoreArea(space.staticIndex, snapshot.staticArea); err != nil {
+		return err
+	}
+
+	space.shapes = make(Shapes, len(snapshot.states))
+	space.kinds = make(map[Shape]BodyKind, len(snapshot.states))
+	space.ids = make(map[Shape]uint64, len(snapshot.states))
+	space.byID = make(map[uint64]Shape, len(snapshot.states))
+	space.idleSteps = map[Shape]int{}
+	space.sleeping = make(Shapes, 0)
+	space.saved = make(map[Shape]*shapeState, len(snapshot.states))
+	space.savedStates = snapshot.states
+	space.nextID = snapshot.nextID
+	space.min = snapshot.min
+	space.max = snapshot.max
+	space.contacts = snapshot.contacts
+	space.shapeHandlers = snapshot.handlers
+	space.removedHandlers = map[Shape]CollisionHandler{}
+	space.handlersShared = true
+
+	for _, state := range snapshot.states {
+		shape := state.shape
+		restoreGeometry(shape, state.geometry)
+		shape.SetIdentity(state.identity)
+		shape.SetMask(state.mask)
+		shape.SetSensor(state.sensor)
+		shape.setID(state.id)
+
+		if err := space.indexOf(state.kind).Insert(shape); err != nil {
+			return err
+		}
+
+		space.shapes.Insert(shape)
+		space.kinds[shape] = state.kind
+		space.ids[shape] = state.id
+		space.byID[state.id] = shape
+		space.saved[shape] = state
+
+		if state.sleeping {
+			space.sleeping.Insert(shape)
+		} else if state.idle > 0 {
+			space.idleSteps[shape] = state.idle
+		}
+	}
+
+	space.resetWrapBox()
+
+	return nil
+}
+
+// restoreArea removes all the shapes from the
+// broadphase and makes it cover the saved area.
+func restoreArea(index Broadphase, area *coveredArea) error {
+	growable, ok := index.(growableBroadphase)
+
+	if !ok || area == nil {
+		return index.Clear()
+	}
+
+	return growable.clearArea(*area)
+}
+
+// sameGeometry returns true if the shape has the same
+// geometry as its copy. Only the position and the angle
+// of the user-defined shapes are compared.
+func sameGeometry(geometry, shape Shape) bool {
+	switch original := shape.(type) {
+	case *Circle:
+		saved := geometry.(*Circle)
+
+		return original.center == saved.center &&
+			original.radius == saved.radius
+
+	case *Line:
+		saved := geometry.(*Line)
+
+		return original.p == saved.p && original.q == saved.q &&
+			original.angle == saved.angle
+
+	case *Rectangle:
+		saved := geometry.(*Rectangle)
+
+		return original.center == saved.center &&
+			original.extents == saved.extents &&
+			original.xAxis == saved.xAxis &&
+			original.yAxis == saved.yAxis &&
+			original.angle == saved.angle
+
+	case *Polygon:
+		saved := geometry.(*Polygon)
+
+		if original.center != saved.center ||
+			original.angle != saved.angle ||
+			len(original.vertices) != len(saved.vertices) {
+			return false
+		}
+
+		for i := range original.vertices {
+			if original.vertices[i] != saved.vertices[i] {
+				return false
+			}
+		}
+
+		return true
+
+	case *Capsule:
+		saved := geometry.(*Capsule)
+
+		return original.p == saved.p && original.q == saved.q &&
+			original.radius == saved.radius &&
+			original.angle == saved.angle
+	}
+
+	return shape.Center() == geometry.Center() &&
+		shape.Angle() == geometry.Angle()
+}
+
+// restoreGeometry copies the geometry of the shape
+// from its copy. Only the position and the angle
+// of the user-defined shapes are restored.
+func restoreGeometry(shape, geometry Shape) {
+	switch original := shape.(type) {
+	case *Circle:
+		saved := geometry.(*Circle)
+		original.center = saved.center
+		original.radius = saved.radius
+
+	case *Line:
+		saved := geometry.(*Line)
+		original.p = saved.p
+		original.q = saved.q
+		original.angle = saved.angle
+
+	case *Rectangle:
+		saved := geometry.(*Rectangle)
+		original.center = saved.center
+		original.extents = saved.extents
+		original.xAxis = saved.xAxis
+		original.yAxis = saved.yAxis
+		original.angle = saved.angle
+
+	case *Polygon:
+		saved := geometry.(*Polygon)
+		original.center = saved.center
+		original.angle = saved.angle
+		original.vertices = make([]Vector, len(saved.vertices))
+		copy(original.vertices, saved.vertices)
+
+	case *Capsule:
+		saved := geometry.(*Capsule)
+		original.p = saved.p
+		original.q = saved.q
+		original.radius = saved.radius
+		original.angle = saved.angle
+
+	default:
+		shape.SetAngle(geometry.Angle())
+		shape.SetPosition(geometry.Center())
+	}
+}
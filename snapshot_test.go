@@ -0,0 +1,202 @@
+package cirno_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zergon321/cirno"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	space, err := cirno.NewSpace(2, 2, 40, 40, cirno.NewVector(-20, -20),
+		cirno.NewVector(20, 20), false)
+	assert.Nil(t, err)
+
+	fighter, err := cirno.NewRectangle(cirno.NewVector(-5, 0), 2, 4, 0)
+	assert.Nil(t, err)
+	rival, err := cirno.NewRectangle(cirno.NewVector(5, 0), 2, 4, 0)
+	assert.Nil(t, err)
+	floor, err := cirno.NewRectangle(cirno.NewVector(0, -3), 30, 2, 0)
+	assert.Nil(t, err)
+	err = space.Add(fighter, rival)
+	assert.Nil(t, err)
+	err = space.AddAs(cirno.BodyStatic, floor)
+	assert.Nil(t, err)
+
+	_, err = space.Step()
+	assert.Nil(t, err)
+	snapshot, err := space.Snapshot()
+	assert.Nil(t, err)
+	assert.Equal(t, 3, snapshot.Len())
+
+	// Simulate a few frames.
+	fighter.Move(cirno.NewVector(9, 0))
+	fighter.Rotate(33)
+	_, err = space.Update(fighter)
+	assert.Nil(t, err)
+	rival.SetIdentity(7)
+	err = space.Remove(rival)
+	assert.Nil(t, err)
+	projectile, err := cirno.NewCircle(cirno.NewVector(0, 5), 1)
+	assert.Nil(t, err)
+	err = space.Add(projectile)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(4), projectile.ID())
+
+	ended := 0
+	err = space.HandleShape(fighter, cirno.CollisionHandler{
+		End: func(shape, other cirno.Shape) { ended++ },
+	})
+	assert.Nil(t, err)
+
+	// Roll back.
+	err = space.Restore(snapshot)
+	assert.Nil(t, err)
+	assert.Equal(t, cirno.NewVector(-5, 0), fighter.Center())
+	assert.Equal(t, 0.0, fighter.Angle())
+	assert.Equal(t, int32(0), rival.GetIdentity())
+
+	contains, err := space.Contains(rival)
+	assert.Nil(t, err)
+	assert.True(t, contains)
+	contains, err = space.Contains(projectile)
+	assert.Nil(t, err)
+	assert.False(t, contains)
+
+	kind, err := space.KindOf(floor)
+	assert.Nil(t, err)
+	assert.Equal(t, cirno.BodyStatic, kind)
+	shape, err := space.ByID(rival.ID())
+	assert.Nil(t, err)
+	assert.Equal(t, rival, shape)
+
+	// The contacts are restored, so the events don't fire again.
+	_, err = space.Step()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, ended)
+
+	shapes, err := space.CollidingWith(fighter)
+	assert.Nil(t, err)
+	assert.Len(t, shapes, 1)
+	assert.Contains(t, shapes, floor)
+
+	// The IDs are assigned again after the rollback.
+	err = space.Add(projectile)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(4), projectile.ID())
+
+	other, err := space.Snapshot()
+	assert.Nil(t, err)
+	assert.Equal(t, 4, other.Len())
+
+	err = space.Restore(nil)
+	assert.NotNil(t, err)
+}
+
+func TestSnapshotSharesUnchangedStates(t *testing.T) {
+	space, err := cirno.NewSpace(5, 10, 200, 200, cirno.NewVector(0, 0),
+		cirno.NewVector(200, 200), false)
+	assert.Nil(t, err)
+
+	circles := []*cirno.Circle{}
+
+	for i := 0; i < 200; i++ {
+		circle, err := cirno.NewCircle(cirno.NewVector(
+			float64(i%20)*10+5, float64(i/20)*10+5), 2)
+		assert.Nil(t, err)
+		err = space.Add(circle)
+		assert.Nil(t, err)
+		circles = append(circles, circle)
+	}
+
+	_, err = space.Snapshot()
+	assert.Nil(t, err)
+
+	// The states of the unchanged shapes are
+	// neither created nor sorted again.
+	allocs := testing.AllocsPerRun(10, func() {
+		_, err = space.Snapshot()
+	})
+	assert.Nil(t, err)
+	assert.LessOrEqual(t, allocs, 8.0)
+
+	// The moved, removed and added again shapes are saved.
+	circles[3].Move(cirno.NewVector(1, 1))
+	_, err = space.Update(circles[3])
+	assert.Nil(t, err)
+	err = space.Remove(circles[0])
+	assert.Nil(t, err)
+	err = space.Add(circles[0])
+	assert.Nil(t, err)
+	err = space.Remove(circles[1])
+	assert.Nil(t, err)
+
+	snapshot, err := space.Snapshot()
+	assert.Nil(t, err)
+	assert.Equal(t, 199, snapshot.Len())
+
+	circles[3].Move(cirno.NewVector(5, 5))
+	_, err = space.Update(circles[3])
+	assert.Nil(t, err)
+	err = space.Remove(circles[0])
+	assert.Nil(t, err)
+
+	err = space.Restore(snapshot)
+	assert.Nil(t, err)
+	assert.Equal(t, cirno.NewVector(36, 6), circles[3].Center())
+	assert.Equal(t, uint64(201), circles[0].ID())
+
+	contains, err := space.Contains(circles[0])
+	assert.Nil(t, err)
+	assert.True(t, contains)
+	contains, err = space.Contains(circles[1])
+	assert.Nil(t, err)
+	assert.False(t, contains)
+
+	ordered := space.Shapes().OrderedItems()
+	assert.Equal(t, circles[2], ordered[0])
+	assert.Equal(t, circles[0], ordered[len(ordered)-1])
+}
+
+func TestSnapshotRestoresGrownArea(t *testing.T) {
+	space, err := cirno.NewSpace(3, 1, 20, 20, cirno.NewVector(0, 0),
+		cirno.NewVector(20, 20), false, cirno.WithBoundsPolicy(cirno.BoundsGrow))
+	assert.Nil(t, err)
+
+	home, err := cirno.NewCircle(cirno.NewVector(5, 5), 1)
+	assert.Nil(t, err)
+	explorer, err := cirno.NewCircle(cirno.NewVector(15, 5), 1)
+	assert.Nil(t, err)
+	err = space.Add(home, explorer)
+	assert.Nil(t, err)
+
+	snapshot, err := space.Snapshot()
+	assert.Nil(t, err)
+	cells := cellAreas(space.Cells())
+
+	// The quad tree root grows after the snapshot.
+	explorer.SetPosition(cirno.NewVector(150, -90))
+	_, err = space.Update(explorer)
+	assert.Nil(t, err)
+	assert.NotEqual(t, cells, cellAreas(space.Cells()))
+
+	err = space.Restore(snapshot)
+	assert.Nil(t, err)
+	assert.Equal(t, cells, cellAreas(space.Cells()))
+	assert.Equal(t, cirno.NewVector(0, 0), space.Min())
+	assert.Equal(t, cirno.NewVector(20, 20), space.Max())
+}
+
+// cellAreas returns the number of
+// shapes in the cells by their areas.
+func cellAreas(cells map[*cirno.Rectangle]cirno.Shapes) map[[2]cirno.Vector]int {
+	areas := map[[2]cirno.Vector]int{}
+
+	for cell, shapes := range cells {
+		areas[[2]cirno.Vector{cell.Center(),
+			cirno.NewVector(cell.Width(), cell.Height())}] = len(shapes)
+	}
+
+	return areas
+}
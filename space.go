@@ -1,11 +1,6 @@
 package cirno
 
-import (
-	"fmt"
-	"reflect"
-
-	"github.com/golang-collections/collections/queue"
-)
+import "fmt"
 
 // Space represents a geometric space
 // with shapes within it.
@@ -13,23 +8,61 @@ type Space struct {
 	min     Vector
 	max     Vector
 	shapes  Shapes
-	tree    *quadTree
+	index   Broadphase
 	useTags bool
+
+	width             float64
+	height            float64
+	subdivisionFactor int
+	shapesInArea      int
+
+	staticIndex Broadphase
+	kinds       map[Shape]BodyKind
+
+	deterministic bool
+	nextID        uint64
+	ids           map[Shape]uint64
+	byID          map[uint64]Shape
+
+	sleepSteps int
+	idleSteps  map[Shape]int
+	sleeping   Shapes
+
+	saved       map[Shape]*shapeState
+	savedStates []*shapeState
+
+	boundsPolicy BoundsPolicy
+	wrapBox      *aabb
+
+	contacts         map[Shape]Shapes
+	shapeHandlers    map[Shape]CollisionHandler
+	removedHandlers  map[Shape]CollisionHandler
+	handlersShared   bool
+	identityHandlers map[int32]CollisionHandler
 }
 
 // Cells returns all the cells the space is subdivided to.
+// The space has no cells if its broadphase doesn't use them.
 func (space *Space) Cells() map[*Rectangle]Shapes {
 	cells := map[*Rectangle]Shapes{}
+	index, ok := space.index.(cellBroadphase)
 
-	for leaf := range space.tree.leaves {
-		cell := leaf.boundary.toRectangle()
+	if !ok {
+		return cells
+	}
 
-		cells[cell] = leaf.shapes.Copy()
+	for boundary, shapes := range index.cells() {
+		cells[boundary.toRectangle()] = shapes
 	}
 
 	return cells
 }
 
+// Broadphase returns the spatial index of the space.
+func (space *Space) Broadphase() Broadphase {
+	return space.index
+}
+
 // Max returns the max point of the space.
 func (space *Space) Max() Vector {
 	return space.max
@@ -55,46 +88,27 @@ func (space *Space) InBounds(shape Shape) (bool, error) {
 
 	pos := shape.Center()
 
-	return pos.X >= space.min.X ||
-		pos.Y >= space.min.Y || pos.X <= space.max.X ||
+	return pos.X >= space.min.X &&
+		pos.Y >= space.min.Y && pos.X <= space.max.X &&
 		pos.Y <= space.max.Y, nil
 }
 
 // AdjustShapePosition changes the position of the shape
-// if it's out of bounds.
+// if it's out of bounds. The shape is clamped with the
+// BoundsIgnore and BoundsClamp policies and wrapped around
+// with the BoundsWrap policy. The BoundsReject policy makes
+// it return an error for the shape out of bounds, and the
+// BoundsGrow policy doesn't change the shape.
 func (space *Space) AdjustShapePosition(shape Shape) error {
 	if shape == nil {
 		return fmt.Errorf("the shape is nil")
 	}
 
-	pos := shape.Center()
-
-	if pos.X < space.min.X {
-		pos = shape.SetPosition(NewVector(space.min.X, pos.Y))
-	}
-
-	if pos.Y < space.min.Y {
-		pos = shape.SetPosition(NewVector(pos.X, space.min.Y))
-	}
-
-	if pos.X > space.max.X {
-		pos = shape.SetPosition(NewVector(space.max.X, pos.Y))
-	}
-
-	if pos.Y > space.max.Y {
-		pos = shape.SetPosition(NewVector(pos.X, space.max.Y))
-	}
-
-	return nil
-}
-
-// Add adds a new shape in the space.
-func (space *Space) Add(shapes ...Shape) error {
-	for _, shape := range shapes {
-		if shape == nil {
-			return fmt.Errorf("the shape is nil")
-		}
+	switch space.boundsPolicy {
+	case BoundsIgnore, BoundsClamp:
+		space.clamp(shape)
 
+	case BoundsReject:
 		inBounds, err := space.InBounds(shape)
 
 		if err != nil {
@@ -105,17 +119,26 @@ func (space *Space) Add(shapes ...Shape) error {
 			return fmt.Errorf("the shape is out of bounds")
 		}
 
-		space.shapes.Insert(shape)
-		_, err = space.tree.insert(shape)
+	case BoundsGrow:
+		// The bounds grow to fit the shape
+		// when it's added or updated.
+		return nil
 
-		if err != nil {
-			return err
-		}
+	case BoundsWrap:
+		space.wrap(shape)
+
+	default:
+		return fmt.Errorf("unknown bounds policy: %d", space.boundsPolicy)
 	}
 
 	return nil
 }
 
+// Add adds new dynamic shapes in the space.
+func (space *Space) Add(shapes ...Shape) error {
+	return space.AddAs(BodyDynamic, shapes...)
+}
+
 // Remove removes the shape from the space.
 func (space *Space) Remove(shapes ...Shape) error {
 	for _, shape := range shapes {
@@ -123,8 +146,18 @@ func (space *Space) Remove(shapes ...Shape) error {
 			return fmt.Errorf("the shape is nil")
 		}
 
+		if _, ok := space.shapes[shape]; !ok {
+			continue
+		}
+
+		kind := space.kinds[shape]
 		space.shapes.Remove(shape)
-		err := space.tree.remove(shape)
+		delete(space.kinds, shape)
+		delete(space.byID, space.ids[shape])
+		delete(space.ids, shape)
+		space.wake(shape)
+		space.dropHandler(shape)
+		err := space.indexOf(kind).Remove(shape)
 
 		if err != nil {
 			return err
@@ -144,12 +177,56 @@ func (space *Space) Contains(shape Shape) (bool, error) {
 	return space.shapes.Contains(shape)
 }
 
+// ByID returns the shape of the space with the given ID.
+func (space *Space) ByID(id uint64) (Shape, error) {
+	shape, ok := space.byID[id]
+
+	if !ok {
+		return nil, fmt.Errorf(
+			"the space doesn't contain the shape with ID %d", id)
+	}
+
+	return shape, nil
+}
+
+// IDOf returns the ID of the shape in the space.
+// The shape added to several spaces has
+// a different ID in each of them.
+func (space *Space) IDOf(shape Shape) (uint64, error) {
+	if shape == nil {
+		return 0, fmt.Errorf("the shape is nil")
+	}
+
+	id, ok := space.ids[shape]
+
+	if !ok {
+		return 0, fmt.Errorf(
+			"the space doesn't contain the given shape")
+	}
+
+	return id, nil
+}
+
 // Clear removes all shapes from
 // the space.
 func (space *Space) Clear() error {
 	space.shapes = make(Shapes, 0)
+	space.kinds = map[Shape]BodyKind{}
+	space.ids = map[Shape]uint64{}
+	space.byID = map[uint64]Shape{}
+	space.idleSteps = map[Shape]int{}
+	space.sleeping = make(Shapes, 0)
+	space.wrapBox = nil
+
+	for shape := range space.shapeHandlers {
+		space.dropHandler(shape)
+	}
 
-	return space.tree.clear()
+	if err := space.staticIndex.Clear(); err != nil {
+		return err
+	}
+
+	return space.index.Clear()
 }
 
 // Shapes returns the list of all shapes
@@ -160,6 +237,7 @@ func (space *Space) Shapes() Shapes {
 
 // Update should be called on the shape
 // whenever it's moved within the space.
+// It applies the bounds policy of the space to the shape.
 func (space *Space) Update(shape Shape) (map[Vector]Shapes, error) {
 	if shape == nil {
 		return nil, fmt.Errorf("the shape is nil")
@@ -175,164 +253,275 @@ func (space *Space) Update(shape Shape) (map[Vector]Shapes, error) {
 		return nil, fmt.Errorf("the space doesn't contain the given shape")
 	}
 
-	// Remove the shape from all the nodes that don't contain it
-	// anymore and remove all these nodes from the shape's domain.
-	nodesToRemove := []*quadTreeNode{}
+	err = space.enforceBounds(shape)
 
-	for _, node := range shape.nodes() {
-		overlapped, err := node.boundary.collidesShape(shape)
+	if err != nil {
+		return nil, err
+	}
 
-		if err != nil {
-			return nil, err
-		}
+	// The nodes the shape was in before the update
+	// to find the nodes it has entered.
+	var previous []*quadTreeNode
 
-		if !overlapped {
-			nodesToRemove = append(nodesToRemove, node)
-		}
+	if space.sleepSteps > 0 {
+		previous = shape.nodes()
 	}
 
-	for _, node := range nodesToRemove {
-		node.shapes.Remove(shape)
-		shape.removeNodes(node)
+	err = space.indexOf(space.kinds[shape]).Update(shape)
+
+	if err != nil {
+		return nil, err
 	}
 
-	// Add the shape in all the nodes
-	// that must be in its domain.
-	nodeQueue := queue.New()
-	nodeQueue.Enqueue(space.tree.root)
+	cells, err := space.cellsOf(shape)
 
-	for nodeQueue.Len() > 0 {
-		node := nodeQueue.Dequeue().(*quadTreeNode)
+	if err != nil {
+		return nil, err
+	}
 
-		// If the node is already
-		// in the domain, skip it.
-		if shape.containsNode(node) {
-			continue
-		}
+	if err := space.wakeEntered(shape, previous); err != nil {
+		return nil, err
+	}
 
-		// If the shape is not covered by the node area,
-		// skip it to the next node.
-		overlapped, err := node.boundary.collidesShape(shape)
+	return cells, nil
+}
+
+// cellsOf returns the shapes of the cells the shape is located
+// in by the cell centers. If the broadphase doesn't use cells,
+// the nearby shapes are returned by the shape center. The static
+// shapes nearby are always returned by the shape center.
+func (space *Space) cellsOf(shape Shape) (map[Vector]Shapes, error) {
+	if index, ok := space.index.(cellBroadphase); ok &&
+		space.kinds[shape] != BodyStatic {
+		cells := index.shapeCells(shape)
+		min, max := shape.GetBoundingBox()
+		statics, err := space.staticIndex.QueryAABB(min, max)
 
 		if err != nil {
 			return nil, err
 		}
 
-		if !overlapped {
-			continue
+		if len(statics) <= 0 {
+			return cells, nil
 		}
 
-		// If the node is not a leaf,
-		// skip it.
-		if node.northWest != nil {
-			nodeQueue.Enqueue(node.northEast)
-			nodeQueue.Enqueue(node.northWest)
-			nodeQueue.Enqueue(node.southEast)
-			nodeQueue.Enqueue(node.southWest)
-
-			continue
+		if shapes, ok := cells[shape.Center()]; ok {
+			shapes.Merge(statics)
+		} else {
+			cells[shape.Center()] = statics
 		}
 
-		// If the node limit is not exceeded,
-		// add the shape in the list of shapes
-		// covered by the node area.
-		if len(node.shapes) < node.tree.nodeCapacity ||
-			node.level >= node.tree.maxLevel {
-			node.shapes.Insert(shape)
-			shape.addNodes(node)
-		} else {
-			// Split the node into four subareas
-			// and add the subnodes in the queue.
-			err := node.split()
+		return cells, nil
+	}
 
-			if err != nil {
-				return nil, err
-			}
+	shapes, err := space.nearby(shape)
 
-			nodeQueue.Enqueue(node.northEast)
-			nodeQueue.Enqueue(node.northWest)
-			nodeQueue.Enqueue(node.southEast)
-			nodeQueue.Enqueue(node.southWest)
-		}
+	if err != nil {
+		return nil, err
 	}
 
-	// Return all the nodes where
-	// the shape is now located in.
-	cells := map[Vector]Shapes{}
+	return map[Vector]Shapes{shape.Center(): shapes}, nil
+}
 
-	for _, node := range shape.nodes() {
-		cells[node.boundary.center()] = node.shapes.Copy()
-	}
+// nearby returns all the shapes the broadphase
+// finds near the bounding box of the shape.
+func (space *Space) nearby(shape Shape) (Shapes, error) {
+	min, max := shape.GetBoundingBox()
 
-	return cells, nil
+	return space.queryAABB(min, max)
 }
 
 // Rebuild rebuilds the space's index
 // of fhapes in purpose to optimize it.
+// The index of the static shapes is
+// rebuilt only by RebuildStatic.
 func (space *Space) Rebuild() error {
-	return space.tree.redistribute()
+	space.resetWrapBox()
+
+	return space.index.Rebuild()
 }
 
 // CollidingShapes returns the dictionary where key
 // is a shape and value is the set of shapes
 // colliding with the key shape.
+//
+// In the wrapped space the shapes also collide
+// across the edges of the space.
 func (space *Space) CollidingShapes() (map[Shape]Shapes, error) {
-	collidingShapes := make(map[Shape]Shapes)
-	shapeGroups := space.tree.shapeGroups()
+	candidates, err := space.candidates()
 
-	for _, area := range shapeGroups {
-		shapes := area.Items()
+	if err != nil {
+		return nil, err
+	}
 
-		for i, shape := range shapes {
-			if _, ok := collidingShapes[shape]; !ok {
-				collidingShapes[shape] = make(Shapes, 0)
-			}
+	overlaps := make([]bool, len(candidates))
 
-			for _, otherShape := range shapes[i+1:] {
-				overlapped, err := ResolveCollision(shape, otherShape, space.useTags)
+	for i, candidate := range candidates {
+		overlapped, err := candidate.resolve(space.useTags)
 
-				if err != nil {
-					return nil, err
-				}
+		if err != nil {
+			return nil, err
+		}
 
-				if overlapped {
-					collidingShapes[shape].Insert(otherShape)
+		overlaps[i] = overlapped
+	}
 
-					if _, ok := collidingShapes[otherShape]; !ok {
-						collidingShapes[otherShape] = make(Shapes, 0)
-					}
+	return collidingShapesOf(candidates, overlaps), nil
+}
 
-					collidingShapes[otherShape].Insert(shape)
-				}
+// candidate is the pair of shapes which might collide.
+// In the wrapped space the image of the first shape
+// across the edges is tested instead of the shape itself.
+type candidate struct {
+	shape Shape
+	image Shape
+	other Shape
+}
+
+// resolve returns true if the shapes of the pair collide.
+func (c candidate) resolve(useTags bool) (bool, error) {
+	return ResolveCollision(c.image, c.other, useTags)
+}
+
+// candidates returns all the pairs of shapes the broadphase
+// finds close to each other. Each pair is returned once.
+func (space *Space) candidates() ([]candidate, error) {
+	candidates := []candidate{}
+	err := space.pairs(func(shape, otherShape Shape) error {
+		if space.deterministic && space.orderedBefore(otherShape, shape) {
+			shape, otherShape = otherShape, shape
+		}
+
+		candidates = append(candidates, candidate{
+			shape: shape,
+			image: shape,
+			other: otherShape,
+		})
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = space.wrappedPairs(func(shape, image, otherShape Shape) error {
+		candidates = append(candidates, candidate{
+			shape: shape,
+			image: image,
+			other: otherShape,
+		})
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if space.deterministic {
+		space.sortCandidates(candidates)
+	}
+
+	return candidates, nil
+}
+
+// wrappedPairs calls the function for every shape, its image
+// across the edges of the wrapped space and the other shape
+// the image might collide. Nothing is done if the space
+// is not wrapped.
+func (space *Space) wrappedPairs(fn func(shape, image, otherShape Shape) error) error {
+	if space.boundsPolicy != BoundsWrap {
+		return nil
+	}
+
+	for _, shape := range space.itemsOf(space.shapes) {
+		images, err := space.imagesOf(shape)
+
+		if err != nil {
+			return err
+		}
+
+		for _, image := range images[1:] {
+			nearby, err := space.nearby(image)
+
+			if err != nil {
+				return err
 			}
 
-			if len(collidingShapes[shape]) == 0 {
-				delete(collidingShapes, shape)
+			for _, otherShape := range space.itemsOf(nearby) {
+				if otherShape == shape || !space.paired(shape, otherShape) {
+					continue
+				}
+
+				if err := fn(shape, image, otherShape); err != nil {
+					return err
+				}
 			}
 		}
 	}
 
-	return collidingShapes, nil
+	return nil
+}
+
+// collidingShapesOf returns the dictionary of colliding
+// shapes for the candidates which overlap.
+func collidingShapesOf(candidates []candidate, overlaps []bool) map[Shape]Shapes {
+	collidingShapes := make(map[Shape]Shapes)
+
+	for i, candidate := range candidates {
+		if !overlaps[i] {
+			continue
+		}
+
+		if _, ok := collidingShapes[candidate.shape]; !ok {
+			collidingShapes[candidate.shape] = make(Shapes, 0)
+		}
+
+		if _, ok := collidingShapes[candidate.other]; !ok {
+			collidingShapes[candidate.other] = make(Shapes, 0)
+		}
+
+		collidingShapes[candidate.shape].Insert(candidate.other)
+		collidingShapes[candidate.other].Insert(candidate.shape)
+	}
+
+	return collidingShapes
 }
 
 // CollidingWith returns the set of shapes colliding with the given shape.
+// In the wrapped space the shapes also collide across the edges.
 func (space *Space) CollidingWith(shape Shape) (Shapes, error) {
 	if shape == nil {
 		return nil, fmt.Errorf("the shape is nil")
 	}
 
 	shapes := make(Shapes, 0)
-	nodes := shape.nodes()
+	images, err := space.imagesOf(shape)
 
-	for _, area := range nodes {
-		for item := range area.shapes {
-			overlapped, err := ResolveCollision(item, shape, space.useTags)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, image := range images {
+		candidates, err := space.nearby(image)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for item := range candidates {
+			if item == shape {
+				continue
+			}
+
+			overlapped, err := ResolveCollision(item, image, space.useTags)
 
 			if err != nil {
 				return nil, err
 			}
 
-			if item != shape && overlapped {
+			if overlapped {
 				shapes.Insert(item)
 			}
 		}
@@ -342,23 +531,38 @@ func (space *Space) CollidingWith(shape Shape) (Shapes, error) {
 }
 
 // CollidedBy returns the set of shapes collided by the given shape.
+// In the wrapped space the shapes also collide across the edges.
 func (space *Space) CollidedBy(shape Shape) (Shapes, error) {
 	if shape == nil {
 		return nil, fmt.Errorf("the shape is nil")
 	}
 
 	shapes := make(Shapes, 0)
-	nodes := shape.nodes()
+	images, err := space.imagesOf(shape)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, image := range images {
+		candidates, err := space.nearby(image)
+
+		if err != nil {
+			return nil, err
+		}
 
-	for _, area := range nodes {
-		for item := range area.shapes {
-			overlapped, err := ResolveCollision(shape, item, space.useTags)
+		for item := range candidates {
+			if item == shape {
+				continue
+			}
+
+			overlapped, err := ResolveCollision(image, item, space.useTags)
 
 			if err != nil {
 				return nil, err
 			}
 
-			if item != shape && overlapped {
+			if overlapped {
 				shapes.Insert(item)
 			}
 		}
@@ -369,6 +573,11 @@ func (space *Space) CollidedBy(shape Shape) (Shapes, error) {
 
 // WouldBeCollidedBy returns all the shapes that would be collided by
 // the given shape if it moved in the specified direction.
+// Sensors are ignored. The moved copy of the shape is tested,
+// so neither the shape nor the space is changed. The user-defined
+// shapes which don't implement Cloner are tested as the circles
+// containing them at any angle. The space with the BoundsReject
+// policy returns an error if the shape would leave the bounds.
 func (space *Space) WouldBeCollidedBy(shape Shape, moveDiff Vector, turnDiff float64) (Shapes, error) {
 	if shape == nil {
 		return nil, fmt.Errorf("the shape is nil")
@@ -377,94 +586,98 @@ func (space *Space) WouldBeCollidedBy(shape Shape, moveDiff Vector, turnDiff flo
 	shapes := make(Shapes, 0)
 	originalPos := shape.Center()
 	originalAngle := shape.Angle()
-	shapeType := reflect.TypeOf(shape).Elem()
 
-	// Get all the nodes where the shape is located
+	// Get all the shapes near the shape
 	// before movement.
-	areas := shape.nodes()
-
-	shape.Move(moveDiff)
-	shape.Rotate(turnDiff)
-	// Make sure the shape is in bounds.
-	space.AdjustShapePosition(shape)
-	// Update the shape's position in the quad tree.
-	nodes, err := space.Update(shape)
+	areas, err := space.nearby(shape)
 
 	if err != nil {
 		return nil, err
 	}
 
-	// Add shapes from the previous nodes.
-	for _, area := range areas {
-		nodes[area.boundary.center()] = area.shapes.Copy()
+	// The moved copy of the shape is tested
+	// instead of the shape itself.
+	ghost, err := ghostOf(shape)
+
+	if err != nil {
+		return nil, err
 	}
 
-	// Search for collisions in the nodes
-	// the shape belongs to.
-	for _, area := range nodes {
-		for item := range area {
-			if item == shape {
-				continue
-			}
+	ghost.Move(moveDiff)
+	ghost.Rotate(turnDiff)
+	// Make sure the shape is in bounds.
+	if err := space.AdjustShapePosition(ghost); err != nil {
+		return nil, err
+	}
 
-			itemType := reflect.TypeOf(item).Elem()
-			id := shapeType.Name() + "_" + itemType.Name()
+	// Get all the shapes near the shape
+	// after movement.
+	moved, err := space.nearby(ghost)
 
-			// Make sure lines will collide.
-			if id == "Line_Line" {
-				lineShape := shape.(*Line)
-				lineItem := item.(*Line)
-				shouldCollide, err := lineShape.ShouldCollide(lineItem)
+	if err != nil {
+		return nil, err
+	}
 
-				if err != nil {
-					return nil, err
-				}
+	areas.Merge(moved)
 
-				if space.useTags && !shouldCollide {
-					continue
-				}
+	// Search for collisions among the shapes
+	// near the shape before and after movement.
+	for item := range areas {
+		if item == shape || !blocks(shape, item) {
+			continue
+		}
 
-				linesWouldIntersect, err := linesWouldCollide(originalPos,
-					originalAngle, moveDiff, turnDiff, lineShape, lineItem)
+		lineShape, shapeIsLine := ghost.(*Line)
+		lineItem, itemIsLine := item.(*Line)
 
-				if err != nil {
-					return nil, err
-				}
+		// Make sure lines will collide.
+		if shapeIsLine && itemIsLine {
+			shouldCollide, err := lineShape.ShouldCollide(lineItem)
 
-				if linesWouldIntersect {
-					shapes.Insert(lineItem)
+			if err != nil {
+				return nil, err
+			}
 
-					continue
-				}
+			if space.useTags && !shouldCollide {
+				continue
 			}
 
-			overlapped, err := ResolveCollision(shape,
-				item, space.useTags)
+			linesWouldIntersect, err := linesWouldCollide(originalPos,
+				originalAngle, moveDiff, turnDiff, lineShape, lineItem)
 
 			if err != nil {
 				return nil, err
 			}
 
-			if overlapped {
-				shapes.Insert(item)
+			if linesWouldIntersect {
+				shapes.Insert(lineItem)
+
+				continue
 			}
 		}
-	}
 
-	// Move the shape back.
-	shape.SetPosition(originalPos)
-	shape.SetAngle(originalAngle)
-	_, err = space.Update(shape)
+		overlapped, err := ResolveCollision(ghost,
+			item, space.useTags)
 
-	if err != nil {
-		return nil, err
+		if err != nil {
+			return nil, err
+		}
+
+		if overlapped {
+			shapes.Insert(item)
+		}
 	}
 
 	return shapes, nil
 }
 
 // WouldBeCollidingWith returns all the shapes that would be colliding the given one
-// if it moved in the specified direction.
+// if it moved in the specified direction. Sensors are ignored. The moved copy
+// of the shape is tested, so neither the shape nor the space is changed.
+// The user-defined shapes which don't implement Cloner are tested
+// as the circles containing them at any angle. The space with the
+// BoundsReject policy returns an error if the shape would leave
+// the bounds.
 func (space *Space) WouldBeCollidingWith(shape Shape, moveDiff Vector, turnDiff float64) (Shapes, error) {
 	if shape == nil {
 		return nil, fmt.Errorf("the shape is nil")
@@ -473,105 +686,115 @@ func (space *Space) WouldBeCollidingWith(shape Shape, moveDiff Vector, turnDiff
 	shapes := make(Shapes, 0)
 	originalPos := shape.Center()
 	originalAngle := shape.Angle()
-	shapeType := reflect.TypeOf(shape).Elem()
 
-	// Get all the nodes where the shape is located
+	// Get all the shapes near the shape
 	// before movement.
-	areas := shape.nodes()
-
-	shape.Move(moveDiff)
-	shape.Rotate(turnDiff)
-	// Make sure the shape is in bounds.
-	space.AdjustShapePosition(shape)
-	// Update the shape's position in the quad tree.
-	nodes, err := space.Update(shape)
+	areas, err := space.nearby(shape)
 
 	if err != nil {
 		return nil, err
 	}
 
-	// Add shapes from the previous nodes.
-	for _, area := range areas {
-		nodes[area.boundary.center()] = area.shapes.Copy()
+	// The moved copy of the shape is tested
+	// instead of the shape itself.
+	ghost, err := ghostOf(shape)
+
+	if err != nil {
+		return nil, err
 	}
 
-	// Search for collisions in the nodes
-	// the shape belongs to.
-	for _, area := range nodes {
-		for item := range area {
-			if item == shape {
-				continue
-			}
+	ghost.Move(moveDiff)
+	ghost.Rotate(turnDiff)
+	// Make sure the shape is in bounds.
+	if err := space.AdjustShapePosition(ghost); err != nil {
+		return nil, err
+	}
 
-			itemType := reflect.TypeOf(item).Elem()
-			id := shapeType.Name() + "_" + itemType.Name()
+	// Get all the shapes near the shape
+	// after movement.
+	moved, err := space.nearby(ghost)
 
-			// Make sure lines will collide.
-			if id == "Line_Line" {
-				lineShape := shape.(*Line)
-				lineItem := item.(*Line)
-				linesCollinear, err := lineShape.CollinearTo(lineItem)
+	if err != nil {
+		return nil, err
+	}
 
-				if err != nil {
-					return nil, err
-				}
+	areas.Merge(moved)
 
-				if linesCollinear {
-					// Check line tags.
-					shouldCollide, err := lineItem.ShouldCollide(lineShape)
+	// Search for collisions among the shapes
+	// near the shape before and after movement.
+	for item := range areas {
+		if item == shape || !blocks(shape, item) {
+			continue
+		}
 
-					if err != nil {
-						return nil, err
-					}
+		lineShape, shapeIsLine := ghost.(*Line)
+		lineItem, itemIsLine := item.(*Line)
 
-					if space.useTags && !shouldCollide {
-						continue
-					}
+		// Make sure lines will collide.
+		if shapeIsLine && itemIsLine {
+			linesCollinear, err := lineShape.CollinearTo(lineItem)
 
-					linesWouldIntersect, err := linesWouldCollide(originalPos,
-						originalAngle, moveDiff, turnDiff, lineShape, lineItem)
+			if err != nil {
+				return nil, err
+			}
 
-					if err != nil {
-						return nil, err
-					}
+			if linesCollinear {
+				// Check line tags.
+				shouldCollide, err := lineItem.ShouldCollide(lineShape)
 
-					if linesWouldIntersect {
-						shapes.Insert(lineItem)
+				if err != nil {
+					return nil, err
+				}
 
-						continue
-					}
+				if space.useTags && !shouldCollide {
+					continue
 				}
-			}
 
-			overlapped, err := ResolveCollision(item,
-				shape, space.useTags)
+				linesWouldIntersect, err := linesWouldCollide(originalPos,
+					originalAngle, moveDiff, turnDiff, lineShape, lineItem)
 
-			if err != nil {
-				return nil, err
-			}
+				if err != nil {
+					return nil, err
+				}
 
-			if overlapped {
-				shapes.Insert(item)
+				if linesWouldIntersect {
+					shapes.Insert(lineItem)
+
+					continue
+				}
 			}
 		}
-	}
 
-	// Move the shape back.
-	shape.SetPosition(originalPos)
-	shape.SetAngle(originalAngle)
-	_, err = space.Update(shape)
+		overlapped, err := ResolveCollision(item,
+			ghost, space.useTags)
 
-	if err != nil {
-		return nil, err
+		if err != nil {
+			return nil, err
+		}
+
+		if overlapped {
+			shapes.Insert(item)
+		}
 	}
 
 	return shapes, nil
 }
 
 // NewSpace creates a new empty space with the given parameters.
+//
+// The space uses the quad tree covering the bounds from min
+// to max as its broadphase unless another one is set up by
+// the options. The quad tree grows beyond the bounds only
+// with the BoundsGrow policy. The width and the height
+// must be positive, but the size of the quad tree
+// is determined by the bounds.
+//
+// The static shapes are kept in the separate AABB tree
+// unless another broadphase is set up for them.
 func NewSpace(
 	subdivisionFactor, shapesInArea int, width,
 	height float64, min, max Vector, useTags bool,
+	options ...SpaceOption,
 ) (
 	*Space, error,
 ) {
@@ -593,11 +816,43 @@ func NewSpace(
 	space := new(Space)
 	space.min = min
 	space.max = max
+	space.width = width
+	space.height = height
+	space.subdivisionFactor = subdivisionFactor
+	space.shapesInArea = shapesInArea
 	space.useTags = useTags
 	space.shapes = make(Shapes, 0)
-	boundary, err := newAABB(NewVector(
-		-width/2.0, -height/2.0),
-		NewVector(width/2.0, height/2.0))
+	space.kinds = map[Shape]BodyKind{}
+	space.ids = map[Shape]uint64{}
+	space.byID = map[uint64]Shape{}
+	space.idleSteps = map[Shape]int{}
+	space.sleeping = make(Shapes, 0)
+	space.contacts = map[Shape]Shapes{}
+	space.shapeHandlers = map[Shape]CollisionHandler{}
+	space.removedHandlers = map[Shape]CollisionHandler{}
+	space.identityHandlers = map[int32]CollisionHandler{}
+
+	for _, option := range options {
+		if err := option(space); err != nil {
+			return nil, err
+		}
+	}
+
+	if space.staticIndex == nil {
+		staticIndex, err := NewAABBTree(0)
+
+		if err != nil {
+			return nil, err
+		}
+
+		space.staticIndex = staticIndex
+	}
+
+	if space.index != nil {
+		return space, nil
+	}
+
+	boundary, err := newAABB(min, max)
 
 	if err != nil {
 		return nil, err
@@ -610,7 +865,7 @@ func NewSpace(
 		return nil, err
 	}
 
-	space.tree = tree
+	space.index = tree
 
 	return space, nil
 }
@@ -238,7 +238,7 @@ func TestOneShapeCollisions(t *testing.T) {
 
 func TestQuadTree(t *testing.T) {
 	space, err := cirno.NewSpace(6, 1, 20, 20,
-		cirno.NewVector(-20, -20), cirno.NewVector(10, 10), false)
+		cirno.NewVector(-10, -10), cirno.NewVector(10, 10), false)
 	assert.Nil(t, err)
 	assert.Equal(t, 1, len(space.Cells()))
 
@@ -274,3 +274,119 @@ func TestQuadTree(t *testing.T) {
 
 	assert.Equal(t, 7, len(space.Cells()))
 }
+
+func TestPredictiveQueriesSideEffects(t *testing.T) {
+	space, err := cirno.NewSpace(6, 1, 20, 20,
+		cirno.NewVector(-10, -10), cirno.NewVector(10, 10), false)
+	assert.Nil(t, err)
+
+	mover, err := cirno.NewRectangle(cirno.NewVector(-5, -5), 1, 1, 0)
+	assert.Nil(t, err)
+	obstacle, err := cirno.NewRectangle(cirno.NewVector(5, 5), 1, 1, 0)
+	assert.Nil(t, err)
+	err = space.Add(mover, obstacle)
+	assert.Nil(t, err)
+
+	cells := len(space.Cells())
+	movement := cirno.NewVector(10, 10)
+
+	shapes, err := space.WouldBeCollidedBy(mover, movement, 45)
+	assert.Nil(t, err)
+	assert.Len(t, shapes, 1)
+	assert.Contains(t, shapes, obstacle)
+
+	shapes, err = space.WouldBeCollidingWith(mover, movement, 45)
+	assert.Nil(t, err)
+	assert.Len(t, shapes, 1)
+
+	pos, _, shape, err := cirno.Approximate(mover, movement, 45,
+		cirno.Shapes{obstacle: {}}, 10, false)
+	assert.Nil(t, err)
+	assert.Equal(t, obstacle, shape)
+	assert.True(t, pos.X < 5)
+
+	// Neither the shape nor the quad tree is changed.
+	assert.Equal(t, cirno.NewVector(-5, -5), mover.Center())
+	assert.Equal(t, 0.0, mover.Angle())
+	assert.Equal(t, cells, len(space.Cells()))
+}
+
+func TestShapeIDs(t *testing.T) {
+	space, err := cirno.NewSpace(2, 2, 40, 40, cirno.NewVector(-20, -20),
+		cirno.NewVector(20, 20), false)
+	assert.Nil(t, err)
+
+	circle, err := cirno.NewCircle(cirno.NewVector(0, 0), 1)
+	assert.Nil(t, err)
+	rect, err := cirno.NewRectangle(cirno.NewVector(5, 5), 2, 2, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), circle.ID())
+
+	err = space.Add(circle, rect)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), circle.ID())
+	assert.Equal(t, uint64(2), rect.ID())
+
+	shape, err := space.ByID(rect.ID())
+	assert.Nil(t, err)
+	assert.Equal(t, rect, shape)
+
+	// The ID is kept while the shape is within the space.
+	err = space.Add(rect)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(2), rect.ID())
+
+	err = space.Remove(circle)
+	assert.Nil(t, err)
+	_, err = space.ByID(1)
+	assert.NotNil(t, err)
+
+	// The IDs are never reused.
+	err = space.Add(circle)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(3), circle.ID())
+}
+
+func TestShapeIDsInSeveralSpaces(t *testing.T) {
+	a, err := cirno.NewSpace(2, 2, 40, 40, cirno.NewVector(-20, -20),
+		cirno.NewVector(20, 20), false)
+	assert.Nil(t, err)
+	b, err := cirno.NewSpace(2, 2, 40, 40, cirno.NewVector(-20, -20),
+		cirno.NewVector(20, 20), false)
+	assert.Nil(t, err)
+
+	c1, err := cirno.NewCircle(cirno.NewVector(0, 0), 1)
+	assert.Nil(t, err)
+	c2, err := cirno.NewCircle(cirno.NewVector(5, 5), 1)
+	assert.Nil(t, err)
+	x, err := cirno.NewCircle(cirno.NewVector(-5, -5), 1)
+	assert.Nil(t, err)
+
+	err = a.Add(c1, c2)
+	assert.Nil(t, err)
+	err = b.Add(x, c1)
+	assert.Nil(t, err)
+
+	// The shape has its own ID in each space.
+	id, err := a.IDOf(c1)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), id)
+	id, err = b.IDOf(c1)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(2), id)
+
+	err = a.Remove(c1)
+	assert.Nil(t, err)
+
+	shape, err := a.ByID(2)
+	assert.Nil(t, err)
+	assert.Equal(t, c2, shape)
+	_, err = a.ByID(1)
+	assert.NotNil(t, err)
+	_, err = a.IDOf(c1)
+	assert.NotNil(t, err)
+
+	shape, err = b.ByID(2)
+	assert.Nil(t, err)
+	assert.Equal(t, c1, shape)
+}
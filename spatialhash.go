@@ -0,0 +1,431 @@
+package cirno
+
+import (
+	"fmt"
+	"math"
+)
+
+// cellKey is the integer coordinates
+// of the spatial hash cell.
+type cellKey struct {
+	x int
+	y int
+}
+
+// cellRange is the rectangular range of
+// the cells covered by the shape's AABB.
+type cellRange struct {
+	min cellKey
+	max cellKey
+}
+
+// count returns the number of the cells in the range.
+func (cr cellRange) count() int {
+	return (cr.max.x - cr.min.x + 1) * (cr.max.y - cr.min.y + 1)
+}
+
+// contains returns true if the cell is within the range.
+func (cr cellRange) contains(key cellKey) bool {
+	return key.x >= cr.min.x && key.x <= cr.max.x &&
+		key.y >= cr.min.y && key.y <= cr.max.y
+}
+
+// spatialHash is a uniform grid of cells of the same size
+// stored in the hash map, so the coordinates of the shapes
+// are not limited. Only the cells containing shapes exist.
+type spatialHash struct {
+	cellSize float64
+	grid     map[cellKey]Shapes
+	ranges   map[Shape]cellRange
+}
+
+// Insert adds the shape in all the cells covered by its AABB.
+// If the shape is already in the grid, it's updated.
+func (hash *spatialHash) Insert(shape Shape) error {
+	if shape == nil {
+		return fmt.Errorf("the shape is nil")
+	}
+
+	if _, ok := hash.ranges[shape]; ok {
+		return hash.Update(shape)
+	}
+
+	cells := hash.rangeOf(shape.GetBoundingBox())
+	hash.ranges[shape] = cells
+	hash.add(shape, cells)
+
+	return nil
+}
+
+// Remove removes the shape from all its cells.
+func (hash *spatialHash) Remove(shape Shape) error {
+	if shape == nil {
+		return fmt.Errorf("the shape is nil")
+	}
+
+	cells, ok := hash.ranges[shape]
+
+	if !ok {
+		return nil
+	}
+
+	hash.remove(shape, cells)
+	delete(hash.ranges, shape)
+
+	return nil
+}
+
+// Update moves the shape to the cells
+// covered by its current AABB.
+func (hash *spatialHash) Update(shape Shape) error {
+	if shape == nil {
+		return fmt.Errorf("the shape is nil")
+	}
+
+	previous, ok := hash.ranges[shape]
+
+	if !ok {
+		return fmt.Errorf("the grid doesn't contain the shape")
+	}
+
+	cells := hash.rangeOf(shape.GetBoundingBox())
+
+	// The shape is still in the same cells.
+	if cells == previous {
+		return nil
+	}
+
+	hash.remove(shape, previous)
+	hash.add(shape, cells)
+	hash.ranges[shape] = cells
+
+	return nil
+}
+
+// QueryAABB returns all the shapes of the
+// cells overlapping the specified box.
+func (hash *spatialHash) QueryAABB(min, max Vector) (Shapes, error) {
+	cells := hash.rangeOf(min, max)
+	shapes := make(Shapes, 0)
+
+	// Don't iterate over the empty
+	// cells of the huge range.
+	if cells.count() > len(hash.grid) {
+		for key, cellShapes := range hash.grid {
+			if cells.contains(key) {
+				shapes.Merge(cellShapes)
+			}
+		}
+
+		return shapes, nil
+	}
+
+	for x := cells.min.x; x <= cells.max.x; x++ {
+		for y := cells.min.y; y <= cells.max.y; y++ {
+			if cellShapes, ok := hash.grid[cellKey{x, y}]; ok {
+				shapes.Merge(cellShapes)
+			}
+		}
+	}
+
+	return shapes, nil
+}
+
+// QueryRay returns all the shapes of the cells crossed by
+// the segment from one point to another. The cells are
+// traversed along the segment one by one.
+func (hash *spatialHash) QueryRay(from, to Vector) (Shapes, error) {
+	shapes := make(Shapes, 0)
+	current := hash.keyOf(from)
+	end := hash.keyOf(to)
+	direction := to.Subtract(from)
+	stepX, tMaxX, tDeltaX := hash.traversalAxis(from.X, direction.X, current.x)
+	stepY, tMaxY, tDeltaY := hash.traversalAxis(from.Y, direction.Y, current.y)
+
+	for {
+		if cellShapes, ok := hash.grid[current]; ok {
+			shapes.Merge(cellShapes)
+		}
+
+		if current == end || math.Min(tMaxX, tMaxY) > 1 {
+			break
+		}
+
+		if tMaxX < tMaxY {
+			current.x += stepX
+			tMaxX += tDeltaX
+		} else {
+			current.y += stepY
+			tMaxY += tDeltaY
+		}
+	}
+
+	return shapes, nil
+}
+
+// traversalAxis returns the step along the axis, the fraction
+// of the segment at which the next cell border along the axis
+// is crossed and the fraction of the segment needed
+// to cross the whole cell along the axis.
+func (hash *spatialHash) traversalAxis(origin, direction float64, cell int) (int, float64, float64) {
+	switch {
+	case direction > 0:
+		border := float64(cell+1) * hash.cellSize
+
+		return 1, (border - origin) / direction,
+			hash.cellSize / direction
+
+	case direction < 0:
+		border := float64(cell) * hash.cellSize
+
+		return -1, (border - origin) / direction,
+			-hash.cellSize / direction
+	}
+
+	return 0, math.Inf(1), math.Inf(1)
+}
+
+// Pairs calls the function for every pair
+// of shapes sharing the same cell.
+func (hash *spatialHash) Pairs(fn func(one, other Shape) error) error {
+	buffer := shapeBuffers.Get().(*[]Shape)
+	defer shapeBuffers.Put(buffer)
+
+	for key, cellShapes := range hash.grid {
+		if err := hash.cellPairs(key, cellShapes, buffer, fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// cellPairs calls the function for every pair of shapes of the cell
+// the cell is the first shared by. The shapes of the cell
+// are listed in the buffer.
+func (hash *spatialHash) cellPairs(key cellKey, cellShapes Shapes, buffer *[]Shape, fn func(one, other Shape) error) error {
+	shapes := (*buffer)[:0]
+
+	for shape := range cellShapes {
+		shapes = append(shapes, shape)
+	}
+
+	*buffer = shapes
+
+	for i, shape := range shapes {
+		shapeCells := hash.ranges[shape]
+
+		for _, other := range shapes[i+1:] {
+			otherCells := hash.ranges[other]
+
+			// The pair is reported only by the first
+			// cell shared by both the shapes.
+			first := cellKey{
+				x: maxInt(shapeCells.min.x, otherCells.min.x),
+				y: maxInt(shapeCells.min.y, otherCells.min.y),
+			}
+
+			if key != first {
+				continue
+			}
+
+			if err := fn(shape, other); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// partitions splits the cells into the parts
+// and returns the function to find the pairs
+// of shapes within each part.
+func (hash *spatialHash) partitions(count int) []func(fn func(one, other Shape) error) error {
+	keys := make([]cellKey, 0, len(hash.grid))
+
+	for key := range hash.grid {
+		keys = append(keys, key)
+	}
+
+	if count > len(keys) {
+		count = len(keys)
+	}
+
+	parts := make([]func(fn func(one, other Shape) error) error, 0, count)
+
+	for i := 0; i < count; i++ {
+		part := keys[i*len(keys)/count : (i+1)*len(keys)/count]
+
+		parts = append(parts, func(fn func(one, other Shape) error) error {
+			buffer := []Shape{}
+
+			for _, key := range part {
+				err := hash.cellPairs(key, hash.grid[key], &buffer, fn)
+
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+	}
+
+	return parts
+}
+
+// Rebuild rehashes all the shapes.
+func (hash *spatialHash) Rebuild() error {
+	shapes := make([]Shape, 0, len(hash.ranges))
+
+	for shape := range hash.ranges {
+		shapes = append(shapes, shape)
+	}
+
+	hash.Clear()
+
+	for _, shape := range shapes {
+		if err := hash.Insert(shape); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Clear removes all the shapes from the grid.
+func (hash *spatialHash) Clear() error {
+	hash.grid = map[cellKey]Shapes{}
+	hash.ranges = map[Shape]cellRange{}
+
+	return nil
+}
+
+// cells returns the boundaries of all the non-empty
+// cells and the shapes within them.
+func (hash *spatialHash) cells() map[*aabb]Shapes {
+	cells := map[*aabb]Shapes{}
+
+	for key, shapes := range hash.grid {
+		cells[hash.boundaryOf(key)] = shapes.Copy()
+	}
+
+	return cells
+}
+
+// shapeCells returns the shapes of all the
+// cells the shape belongs to by their centers.
+func (hash *spatialHash) shapeCells(shape Shape) map[Vector]Shapes {
+	cells := map[Vector]Shapes{}
+	shapeRange, ok := hash.ranges[shape]
+
+	if !ok {
+		return cells
+	}
+
+	for x := shapeRange.min.x; x <= shapeRange.max.x; x++ {
+		for y := shapeRange.min.y; y <= shapeRange.max.y; y++ {
+			key := cellKey{x, y}
+
+			if shapes, ok := hash.grid[key]; ok {
+				cells[hash.boundaryOf(key).center()] = shapes.Copy()
+			}
+		}
+	}
+
+	return cells
+}
+
+// add adds the shape in all the cells of the range.
+func (hash *spatialHash) add(shape Shape, cells cellRange) {
+	for x := cells.min.x; x <= cells.max.x; x++ {
+		for y := cells.min.y; y <= cells.max.y; y++ {
+			key := cellKey{x, y}
+			cellShapes, ok := hash.grid[key]
+
+			if !ok {
+				cellShapes = make(Shapes, 0)
+				hash.grid[key] = cellShapes
+			}
+
+			cellShapes.Insert(shape)
+		}
+	}
+}
+
+// remove removes the shape from all the cells
+// of the range and drops the empty cells.
+func (hash *spatialHash) remove(shape Shape, cells cellRange) {
+	for x := cells.min.x; x <= cells.max.x; x++ {
+		for y := cells.min.y; y <= cells.max.y; y++ {
+			key := cellKey{x, y}
+			cellShapes, ok := hash.grid[key]
+
+			if !ok {
+				continue
+			}
+
+			cellShapes.Remove(shape)
+
+			if len(cellShapes) <= 0 {
+				delete(hash.grid, key)
+			}
+		}
+	}
+}
+
+// keyOf returns the key of the cell containing the point.
+func (hash *spatialHash) keyOf(point Vector) cellKey {
+	return cellKey{
+		x: int(math.Floor(point.X / hash.cellSize)),
+		y: int(math.Floor(point.Y / hash.cellSize)),
+	}
+}
+
+// rangeOf returns the range of the cells
+// covered by the box with the min and max points.
+func (hash *spatialHash) rangeOf(min, max Vector) cellRange {
+	return cellRange{
+		min: hash.keyOf(min),
+		max: hash.keyOf(max),
+	}
+}
+
+// boundaryOf returns the AABB of the cell.
+func (hash *spatialHash) boundaryOf(key cellKey) *aabb {
+	min := NewVector(float64(key.x)*hash.cellSize,
+		float64(key.y)*hash.cellSize)
+
+	return &aabb{
+		min: min,
+		max: min.Add(NewVector(hash.cellSize, hash.cellSize)),
+	}
+}
+
+// maxInt returns the greater of two integers.
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
+
+// NewSpatialHash returns a new broadphase based on
+// the uniform grid of the cells of the specified size.
+// The grid has no bounds.
+//
+// It works best when the shapes are about the same size
+// and the cell is slightly bigger than a shape.
+func NewSpatialHash(cellSize float64) (Broadphase, error) {
+	if cellSize <= 0 {
+		return nil, fmt.Errorf("the cell size must be positive")
+	}
+
+	return &spatialHash{
+		cellSize: cellSize,
+		grid:     map[cellKey]Shapes{},
+		ranges:   map[Shape]cellRange{},
+	}, nil
+}
@@ -0,0 +1,214 @@
+package cirno
+
+import (
+	"fmt"
+	"sort"
+)
+
+// sapEntry is the shape with its AABB
+// indexed by the sweep and prune.
+type sapEntry struct {
+	shape Shape
+	box   *aabb
+}
+
+// sweepAndPrune keeps the AABBs of the shapes sorted
+// along the X axis, so only the shapes overlapping
+// along X are checked against each other.
+type sweepAndPrune struct {
+	entries []*sapEntry
+	byShape map[Shape]*sapEntry
+	sorted  bool
+}
+
+// Insert adds the shape in the index.
+// If the shape is already indexed, it's updated.
+func (sap *sweepAndPrune) Insert(shape Shape) error {
+	if shape == nil {
+		return fmt.Errorf("the shape is nil")
+	}
+
+	if _, ok := sap.byShape[shape]; ok {
+		return sap.Update(shape)
+	}
+
+	entry := &sapEntry{
+		shape: shape,
+		box:   boundingBoxOf(shape),
+	}
+	sap.entries = append(sap.entries, entry)
+	sap.byShape[shape] = entry
+	sap.sorted = false
+
+	return nil
+}
+
+// Remove removes the shape from the index.
+func (sap *sweepAndPrune) Remove(shape Shape) error {
+	if shape == nil {
+		return fmt.Errorf("the shape is nil")
+	}
+
+	entry, ok := sap.byShape[shape]
+
+	if !ok {
+		return nil
+	}
+
+	for i, other := range sap.entries {
+		if other == entry {
+			sap.entries = append(sap.entries[:i], sap.entries[i+1:]...)
+
+			break
+		}
+	}
+
+	delete(sap.byShape, shape)
+
+	return nil
+}
+
+// Update updates the AABB of the shape.
+func (sap *sweepAndPrune) Update(shape Shape) error {
+	if shape == nil {
+		return fmt.Errorf("the shape is nil")
+	}
+
+	entry, ok := sap.byShape[shape]
+
+	if !ok {
+		return fmt.Errorf("the index doesn't contain the shape")
+	}
+
+	entry.box = boundingBoxOf(shape)
+	sap.sorted = false
+
+	return nil
+}
+
+// QueryAABB returns all the shapes whose
+// AABBs overlap the specified box.
+func (sap *sweepAndPrune) QueryAABB(min, max Vector) (Shapes, error) {
+	box := &aabb{min: min, max: max}
+	shapes := make(Shapes, 0)
+
+	sap.sort()
+
+	for _, entry := range sap.entries {
+		if entry.box.min.X > box.max.X {
+			break
+		}
+
+		if entry.box.overlaps(box) {
+			shapes.Insert(entry.shape)
+		}
+	}
+
+	return shapes, nil
+}
+
+// QueryRay returns all the shapes whose AABBs are
+// crossed by the segment from one point to another.
+func (sap *sweepAndPrune) QueryRay(from, to Vector) (Shapes, error) {
+	box := (&aabb{min: from, max: from}).
+		union(&aabb{min: to, max: to})
+	shapes := make(Shapes, 0)
+
+	sap.sort()
+
+	for _, entry := range sap.entries {
+		if entry.box.min.X > box.max.X {
+			break
+		}
+
+		if entry.box.overlaps(box) &&
+			entry.box.collidesSegment(from, to) {
+			shapes.Insert(entry.shape)
+		}
+	}
+
+	return shapes, nil
+}
+
+// Pairs sweeps the sorted AABBs along the X axis and calls
+// the function for every pair of the overlapping ones.
+func (sap *sweepAndPrune) Pairs(fn func(one, other Shape) error) error {
+	sap.sort()
+
+	for i, entry := range sap.entries {
+		for _, other := range sap.entries[i+1:] {
+			// All the next AABBs start
+			// after the current one ends.
+			if other.box.min.X > entry.box.max.X {
+				break
+			}
+
+			if !entry.box.overlaps(other.box) {
+				continue
+			}
+
+			if err := fn(entry.shape, other.shape); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Rebuild sorts the AABBs from scratch.
+func (sap *sweepAndPrune) Rebuild() error {
+	sort.SliceStable(sap.entries, func(i, j int) bool {
+		return sap.entries[i].box.min.X < sap.entries[j].box.min.X
+	})
+	sap.sorted = true
+
+	return nil
+}
+
+// Clear removes all the shapes from the index.
+func (sap *sweepAndPrune) Clear() error {
+	sap.entries = []*sapEntry{}
+	sap.byShape = map[Shape]*sapEntry{}
+	sap.sorted = true
+
+	return nil
+}
+
+// prepare sorts the AABBs, so
+// the queries don't modify the index.
+func (sap *sweepAndPrune) prepare() {
+	sap.sort()
+}
+
+// sort sorts the AABBs by their min X coordinates
+// using insertion sort which is fast when the shapes
+// have moved a little since the last sort.
+func (sap *sweepAndPrune) sort() {
+	if sap.sorted {
+		return
+	}
+
+	for i := 1; i < len(sap.entries); i++ {
+		entry := sap.entries[i]
+		j := i - 1
+
+		for ; j >= 0 && sap.entries[j].box.min.X > entry.box.min.X; j-- {
+			sap.entries[j+1] = sap.entries[j]
+		}
+
+		sap.entries[j+1] = entry
+	}
+
+	sap.sorted = true
+}
+
+// NewSweepAndPrune returns a new broadphase
+// based on the sweep and prune algorithm.
+func NewSweepAndPrune() Broadphase {
+	return &sweepAndPrune{
+		entries: []*sapEntry{},
+		byShape: map[Shape]*sapEntry{},
+		sorted:  true,
+	}
+}
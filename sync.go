@@ -0,0 +1,449 @@
+package cirno
+
+import (
+	"fmt"
+	"sync"
+)
+
+// lazyBroadphase is a broadphase which defers some
+// work on modification until it's queried.
+type lazyBroadphase interface {
+	// prepare does all the deferred work, so the
+	// queries don't modify the broadphase.
+	prepare()
+}
+
+// SyncSpace is the wrapper around the space
+// which is safe for concurrent use.
+//
+// The read queries are run in parallel under the read lock.
+// The methods modifying the space, its shapes or handlers
+// are serialized under the write lock, so they never run
+// along with the read queries.
+//
+// The shapes added to the synchronized space must be moved,
+// rotated and modified only via its methods. The custom
+// broadphase of the space must allow concurrent queries.
+type SyncSpace struct {
+	mutex sync.RWMutex
+	space *Space
+}
+
+// Add adds new dynamic shapes in the space.
+func (ss *SyncSpace) Add(shapes ...Shape) error {
+	ss.mutex.Lock()
+	defer ss.unlock()
+
+	return ss.space.Add(shapes...)
+}
+
+// AddAs adds new shapes of the specified kind in the space.
+func (ss *SyncSpace) AddAs(kind BodyKind, shapes ...Shape) error {
+	ss.mutex.Lock()
+	defer ss.unlock()
+
+	return ss.space.AddAs(kind, shapes...)
+}
+
+// Remove removes the shapes from the space.
+func (ss *SyncSpace) Remove(shapes ...Shape) error {
+	ss.mutex.Lock()
+	defer ss.unlock()
+
+	return ss.space.Remove(shapes...)
+}
+
+// Clear removes all shapes from the space.
+func (ss *SyncSpace) Clear() error {
+	ss.mutex.Lock()
+	defer ss.unlock()
+
+	return ss.space.Clear()
+}
+
+// Update should be called on the shape whenever
+// it's changed within the Do function.
+func (ss *SyncSpace) Update(shape Shape) (map[Vector]Shapes, error) {
+	ss.mutex.Lock()
+	defer ss.unlock()
+
+	return ss.space.Update(shape)
+}
+
+// Move moves the shape by the specified
+// vector and updates it in the space.
+func (ss *SyncSpace) Move(shape Shape, moveDiff Vector) error {
+	if shape == nil {
+		return fmt.Errorf("the shape is nil")
+	}
+
+	ss.mutex.Lock()
+	defer ss.unlock()
+
+	shape.Move(moveDiff)
+	_, err := ss.space.Update(shape)
+
+	return err
+}
+
+// Rotate rotates the shape at the specified
+// angle (in degrees) and updates it in the space.
+func (ss *SyncSpace) Rotate(shape Shape, turnDiff float64) error {
+	if shape == nil {
+		return fmt.Errorf("the shape is nil")
+	}
+
+	ss.mutex.Lock()
+	defer ss.unlock()
+
+	shape.Rotate(turnDiff)
+	_, err := ss.space.Update(shape)
+
+	return err
+}
+
+// Rebuild rebuilds the space's index of shapes.
+func (ss *SyncSpace) Rebuild() error {
+	ss.mutex.Lock()
+	defer ss.unlock()
+
+	return ss.space.Rebuild()
+}
+
+// RebuildStatic rebuilds the index of the static shapes.
+func (ss *SyncSpace) RebuildStatic() error {
+	ss.mutex.Lock()
+	defer ss.unlock()
+
+	return ss.space.RebuildStatic()
+}
+
+// Snapshot saves the current state of the space.
+func (ss *SyncSpace) Snapshot() (*Snapshot, error) {
+	ss.mutex.Lock()
+	defer ss.unlock()
+
+	return ss.space.Snapshot()
+}
+
+// Restore brings the space back to
+// the state saved in the snapshot.
+func (ss *SyncSpace) Restore(snapshot *Snapshot) error {
+	ss.mutex.Lock()
+	defer ss.unlock()
+
+	return ss.space.Restore(snapshot)
+}
+
+// AdjustShapePosition changes the position
+// of the shape if it's out of bounds.
+func (ss *SyncSpace) AdjustShapePosition(shape Shape) error {
+	ss.mutex.Lock()
+	defer ss.unlock()
+
+	return ss.space.AdjustShapePosition(shape)
+}
+
+// HandleShape sets the collision handler for the shape.
+func (ss *SyncSpace) HandleShape(shape Shape, handler CollisionHandler) error {
+	ss.mutex.Lock()
+	defer ss.unlock()
+
+	return ss.space.HandleShape(shape, handler)
+}
+
+// RemoveShapeHandler removes the collision handler of the shape.
+func (ss *SyncSpace) RemoveShapeHandler(shape Shape) error {
+	ss.mutex.Lock()
+	defer ss.unlock()
+
+	return ss.space.RemoveShapeHandler(shape)
+}
+
+// HandleIdentity sets the collision handler
+// for the shapes of the identity.
+func (ss *SyncSpace) HandleIdentity(identity int32, handler CollisionHandler) {
+	ss.mutex.Lock()
+	defer ss.unlock()
+
+	ss.space.HandleIdentity(identity, handler)
+}
+
+// RemoveIdentityHandler removes the
+// collision handler of the identity.
+func (ss *SyncSpace) RemoveIdentityHandler(identity int32) {
+	ss.mutex.Lock()
+	defer ss.unlock()
+
+	ss.space.RemoveIdentityHandler(identity)
+}
+
+// Wake wakes up the shape.
+func (ss *SyncSpace) Wake(shape Shape) error {
+	ss.mutex.Lock()
+	defer ss.unlock()
+
+	return ss.space.Wake(shape)
+}
+
+// IsSleeping returns true if the shape is sleeping.
+func (ss *SyncSpace) IsSleeping(shape Shape) (bool, error) {
+	ss.mutex.RLock()
+	defer ss.mutex.RUnlock()
+
+	return ss.space.IsSleeping(shape)
+}
+
+// Step finds the colliding shapes and calls the collision
+// handlers. The handlers are called under the write lock,
+// so they must not call the methods of the synchronized space.
+func (ss *SyncSpace) Step() (map[Shape]Shapes, error) {
+	ss.mutex.Lock()
+	defer ss.unlock()
+
+	return ss.space.Step()
+}
+
+// WouldBeCollidedBy returns all the shapes that would be
+// collided by the given shape if it moved and rotated.
+func (ss *SyncSpace) WouldBeCollidedBy(shape Shape, moveDiff Vector, turnDiff float64) (Shapes, error) {
+	ss.mutex.RLock()
+	defer ss.mutex.RUnlock()
+
+	return ss.space.WouldBeCollidedBy(shape, moveDiff, turnDiff)
+}
+
+// WouldBeCollidingWith returns all the shapes that would
+// be colliding the given one if it moved and rotated.
+func (ss *SyncSpace) WouldBeCollidingWith(shape Shape, moveDiff Vector, turnDiff float64) (Shapes, error) {
+	ss.mutex.RLock()
+	defer ss.mutex.RUnlock()
+
+	return ss.space.WouldBeCollidingWith(shape, moveDiff, turnDiff)
+}
+
+// Do calls the function on the space under the write lock.
+// The shapes changed by the function should be updated in it.
+func (ss *SyncSpace) Do(fn func(space *Space) error) error {
+	ss.mutex.Lock()
+	defer ss.unlock()
+
+	return fn(ss.space)
+}
+
+// View calls the function on the space under the read lock.
+// The function must not change the space and its shapes.
+func (ss *SyncSpace) View(fn func(space *Space) error) error {
+	ss.mutex.RLock()
+	defer ss.mutex.RUnlock()
+
+	return fn(ss.space)
+}
+
+// Raycast casts a ray in the space and returns the hit shape closest
+// to the origin of the ray and the hit point.
+func (ss *SyncSpace) Raycast(origin, direction Vector, distance float64, mask int32) (Shape, Vector, error) {
+	ss.mutex.RLock()
+	defer ss.mutex.RUnlock()
+
+	return ss.space.Raycast(origin, direction, distance, mask)
+}
+
+// RaycastHit casts a ray in the space and returns
+// the hit closest to the origin of the ray.
+func (ss *SyncSpace) RaycastHit(origin, direction Vector, distance float64, mask int32, includeInner bool) (RaycastHit, bool, error) {
+	ss.mutex.RLock()
+	defer ss.mutex.RUnlock()
+
+	return ss.space.RaycastHit(origin, direction, distance, mask, includeInner)
+}
+
+// RaycastAll casts a ray in the space and returns all
+// the hits sorted by the distance from the origin.
+func (ss *SyncSpace) RaycastAll(origin, direction Vector, distance float64, mask int32, includeInner bool) ([]RaycastHit, error) {
+	ss.mutex.RLock()
+	defer ss.mutex.RUnlock()
+
+	return ss.space.RaycastAll(origin, direction, distance, mask, includeInner)
+}
+
+// Boxcast returns all the shapes of the space
+// overlapping the given rectangle.
+func (ss *SyncSpace) Boxcast(rect *Rectangle) (Shapes, error) {
+	ss.mutex.RLock()
+	defer ss.mutex.RUnlock()
+
+	return ss.space.Boxcast(rect)
+}
+
+// Circlecast returns all the shapes of the space
+// overlapping the given circle.
+func (ss *SyncSpace) Circlecast(circle *Circle) (Shapes, error) {
+	ss.mutex.RLock()
+	defer ss.mutex.RUnlock()
+
+	return ss.space.Circlecast(circle)
+}
+
+// ShapeCast returns the first shape the given
+// shape would hit moving in the direction.
+func (ss *SyncSpace) ShapeCast(shape Shape, direction Vector, distance float64, mask int32) (Shape, float64, Vector, Vector, error) {
+	ss.mutex.RLock()
+	defer ss.mutex.RUnlock()
+
+	return ss.space.ShapeCast(shape, direction, distance, mask)
+}
+
+// SweepFirst returns the first shape the given
+// shape would touch if it moved and rotated.
+func (ss *SyncSpace) SweepFirst(shape Shape, moveDiff Vector, turnDiff float64) (Shape, float64, Vector, error) {
+	ss.mutex.RLock()
+	defer ss.mutex.RUnlock()
+
+	return ss.space.SweepFirst(shape, moveDiff, turnDiff)
+}
+
+// CollidingShapes returns the dictionary where key
+// is a shape and value is the set of shapes
+// colliding with the key shape.
+func (ss *SyncSpace) CollidingShapes() (map[Shape]Shapes, error) {
+	ss.mutex.RLock()
+	defer ss.mutex.RUnlock()
+
+	return ss.space.CollidingShapes()
+}
+
+// CollidingShapesParallel returns the dictionary of colliding shapes
+// using the specified number of goroutines for collision detection.
+func (ss *SyncSpace) CollidingShapesParallel(workers int) (map[Shape]Shapes, error) {
+	ss.mutex.RLock()
+	defer ss.mutex.RUnlock()
+
+	return ss.space.CollidingShapesParallel(workers)
+}
+
+// CollisionPairs returns all the pairs of colliding shapes.
+func (ss *SyncSpace) CollisionPairs() ([]CollisionPair, error) {
+	ss.mutex.RLock()
+	defer ss.mutex.RUnlock()
+
+	return ss.space.CollisionPairs()
+}
+
+// EachCollisionPair calls the function for every pair of
+// colliding shapes. The function is called under the read
+// lock, so it must not change the synchronized space.
+func (ss *SyncSpace) EachCollisionPair(fn func(pair CollisionPair) error) error {
+	ss.mutex.RLock()
+	defer ss.mutex.RUnlock()
+
+	return ss.space.EachCollisionPair(fn)
+}
+
+// CollidingWith returns the set of shapes colliding with the given shape.
+func (ss *SyncSpace) CollidingWith(shape Shape) (Shapes, error) {
+	ss.mutex.RLock()
+	defer ss.mutex.RUnlock()
+
+	return ss.space.CollidingWith(shape)
+}
+
+// CollidedBy returns the set of shapes collided by the given shape.
+func (ss *SyncSpace) CollidedBy(shape Shape) (Shapes, error) {
+	ss.mutex.RLock()
+	defer ss.mutex.RUnlock()
+
+	return ss.space.CollidedBy(shape)
+}
+
+// Contains returns true if the shape is within the space.
+func (ss *SyncSpace) Contains(shape Shape) (bool, error) {
+	ss.mutex.RLock()
+	defer ss.mutex.RUnlock()
+
+	return ss.space.Contains(shape)
+}
+
+// ByID returns the shape of the space with the given ID.
+func (ss *SyncSpace) ByID(id uint64) (Shape, error) {
+	ss.mutex.RLock()
+	defer ss.mutex.RUnlock()
+
+	return ss.space.ByID(id)
+}
+
+// IDOf returns the ID of the shape in the space.
+func (ss *SyncSpace) IDOf(shape Shape) (uint64, error) {
+	ss.mutex.RLock()
+	defer ss.mutex.RUnlock()
+
+	return ss.space.IDOf(shape)
+}
+
+// Ordered returns the shapes sorted by their IDs in the space.
+func (ss *SyncSpace) Ordered(shapes Shapes) []Shape {
+	ss.mutex.RLock()
+	defer ss.mutex.RUnlock()
+
+	return ss.space.Ordered(shapes)
+}
+
+// KindOf returns the kind of the shape in the space.
+func (ss *SyncSpace) KindOf(shape Shape) (BodyKind, error) {
+	ss.mutex.RLock()
+	defer ss.mutex.RUnlock()
+
+	return ss.space.KindOf(shape)
+}
+
+// Shapes returns the list of all shapes within the space.
+func (ss *SyncSpace) Shapes() Shapes {
+	ss.mutex.RLock()
+	defer ss.mutex.RUnlock()
+
+	return ss.space.Shapes()
+}
+
+// Min returns the min point of the space.
+func (ss *SyncSpace) Min() Vector {
+	ss.mutex.RLock()
+	defer ss.mutex.RUnlock()
+
+	return ss.space.Min()
+}
+
+// Max returns the max point of the space.
+func (ss *SyncSpace) Max() Vector {
+	ss.mutex.RLock()
+	defer ss.mutex.RUnlock()
+
+	return ss.space.Max()
+}
+
+// unlock prepares the indices of the space
+// for the concurrent queries and releases
+// the write lock.
+func (ss *SyncSpace) unlock() {
+	for _, index := range []Broadphase{ss.space.index, ss.space.staticIndex} {
+		if index, ok := index.(lazyBroadphase); ok {
+			index.prepare()
+		}
+	}
+
+	ss.mutex.Unlock()
+}
+
+// NewSyncSpace returns the wrapper around the space
+// which is safe for concurrent use. The space must
+// not be used directly after it's wrapped.
+func NewSyncSpace(space *Space) (*SyncSpace, error) {
+	if space == nil {
+		return nil, fmt.Errorf("the space is nil")
+	}
+
+	ss := &SyncSpace{space: space}
+	ss.mutex.Lock()
+	ss.unlock()
+
+	return ss, nil
+}
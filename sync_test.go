@@ -0,0 +1,69 @@
+package cirno_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zergon321/cirno"
+)
+
+func TestSyncSpace(t *testing.T) {
+	_, err := cirno.NewSyncSpace(nil)
+	assert.NotNil(t, err)
+
+	sap := cirno.NewSweepAndPrune()
+	space, err := cirno.NewSpace(2, 4, 40, 40, cirno.NewVector(-20, -20),
+		cirno.NewVector(20, 20), false, cirno.WithBroadphase(sap))
+	assert.Nil(t, err)
+	ss, err := cirno.NewSyncSpace(space)
+	assert.Nil(t, err)
+
+	walker, err := cirno.NewCircle(cirno.NewVector(-10, 0), 1)
+	assert.Nil(t, err)
+	wall, err := cirno.NewRectangle(cirno.NewVector(10, 0), 2, 10, 0)
+	assert.Nil(t, err)
+	err = ss.Add(walker)
+	assert.Nil(t, err)
+	err = ss.AddAs(cirno.BodyStatic, wall)
+	assert.Nil(t, err)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				hit, _, err := ss.Raycast(cirno.NewVector(-15, 0),
+					cirno.Right(), 30, 0)
+				assert.Nil(t, err)
+				assert.NotNil(t, hit)
+
+				_, err = ss.CollidingWith(walker)
+				assert.Nil(t, err)
+
+				_, err = ss.WouldBeCollidedBy(walker,
+					cirno.NewVector(1, 0), 0)
+				assert.Nil(t, err)
+			}
+		}()
+	}
+
+	for i := 0; i < 100; i++ {
+		err = ss.Move(walker, cirno.NewVector(0.1, 0))
+		assert.Nil(t, err)
+	}
+
+	wg.Wait()
+
+	err = ss.View(func(space *cirno.Space) error {
+		assert.True(t, walker.Center().ApproximatelyEqual(cirno.NewVector(0, 0)))
+
+		return nil
+	})
+	assert.Nil(t, err)
+}
@@ -11,6 +11,9 @@ type tag struct {
 	// Mask determines which other shapes
 	// the present shape can collide.
 	mask int32
+	// Sensor shapes report overlaps
+	// but never block other shapes.
+	sensor bool
 }
 
 // ShouldCollide returns true if the shape should
@@ -42,3 +45,21 @@ func (t tag) GetMask() int32 {
 func (t *tag) SetMask(newMask int32) {
 	t.mask = newMask
 }
+
+// IsSensor returns true if the shape is a sensor.
+// Sensors are found by overlap queries but are
+// ignored when the shape movement is blocked.
+func (t tag) IsSensor() bool {
+	return t.sensor
+}
+
+// SetSensor makes the shape a sensor or a regular shape.
+func (t *tag) SetSensor(sensor bool) {
+	t.sensor = sensor
+}
+
+// blocks returns true if the shapes
+// can block each other's movement.
+func blocks(one, other Shape) bool {
+	return !one.IsSensor() && !other.IsSensor()
+}
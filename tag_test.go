@@ -63,3 +63,47 @@ func TestControversy(t *testing.T) {
 	assert.True(t, res0)
 	assert.False(t, res1)
 }
+
+func TestSensors(t *testing.T) {
+	player, err := cirno.NewRectangle(cirno.NewVector(5, 5), 2, 2, 0)
+	assert.Nil(t, err)
+	coin, err := cirno.NewCircle(cirno.NewVector(9, 5), 1)
+	assert.Nil(t, err)
+	coin.SetSensor(true)
+	wall, err := cirno.NewRectangle(cirno.NewVector(14, 5), 2, 10, 0)
+	assert.Nil(t, err)
+
+	space, err := cirno.NewSpace(2, 10, 64, 64,
+		cirno.NewVector(0, 0), cirno.NewVector(32, 32), false)
+	assert.Nil(t, err)
+	err = space.Add(player, coin, wall)
+	assert.Nil(t, err)
+
+	movement := cirno.NewVector(10, 0)
+	shapes, err := space.WouldBeCollidedBy(player, movement, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(shapes))
+	assert.Contains(t, shapes, wall)
+
+	shapes, err = space.WouldBeCollidingWith(player, movement, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(shapes))
+
+	pos, _, shape, err := cirno.Approximate(player, movement, 0,
+		cirno.Shapes{coin: {}, wall: {}}, 100, false)
+	assert.Nil(t, err)
+	assert.Equal(t, wall, shape)
+	assert.InDelta(t, 12, pos.X, 0.0001)
+
+	shape, _, _, err = space.SweepFirst(player, movement, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, wall, shape)
+
+	// The sensor is still found by overlap queries.
+	player.SetPosition(cirno.NewVector(8, 5))
+	_, err = space.Update(player)
+	assert.Nil(t, err)
+	shapes, err = space.CollidingWith(player)
+	assert.Nil(t, err)
+	assert.Contains(t, shapes, coin)
+}
@@ -0,0 +1,309 @@
+package cirno
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	// TOITolerance is the distance at which the shapes
+	// are considered touching by time of impact queries.
+	TOITolerance float64 = 0.0001
+	// toiMaxIterations limits the number of
+	// conservative advancement steps.
+	toiMaxIterations = 64
+)
+
+// TimeOfImpact returns the fraction of the movement (from 0 to 1)
+// at which the shape moved by moveDiff and rotated by turnDiff
+// (in degrees) first touches the other shape, and the normal
+// from the shape to the other one at the moment of impact.
+//
+// The other shape is considered static. If the shapes
+// overlap at the start, the time of impact is 0.
+//
+// The user-defined shapes are swept as their bounding boxes
+// taken at the start of the movement, which contain them at
+// any angle, so the impact with them may be reported earlier
+// than it really happens but is never missed.
+func TimeOfImpact(shape Shape, moveDiff Vector, turnDiff float64, other Shape) (t float64, normal Vector, hit bool, err error) {
+	if shape == nil {
+		return 0, Zero(), false,
+			fmt.Errorf("the shape is nil")
+	}
+
+	if other == nil {
+		return 0, Zero(), false,
+			fmt.Errorf("the other shape is nil")
+	}
+
+	t, normal, _, hit = timeOfImpactCores(sweptCoreOf(shape),
+		shape.Center(), moveDiff, turnDiff, sweptCoreOf(other))
+
+	return t, normal, hit, nil
+}
+
+// sweptCoreOf returns the convex core of the shape for the
+// time of impact queries. The user-defined shapes are
+// replaced by their bounding boxes.
+func sweptCoreOf(shape Shape) convexCore {
+	if core, ok := coreOf(shape); ok {
+		return core
+	}
+
+	min, max := shape.GetBoundingBox()
+	vertices := []Vector{min}
+
+	// Don't repeat the vertices
+	// of the degenerate box.
+	switch {
+	case min.X != max.X && min.Y != max.Y:
+		vertices = append(vertices, NewVector(max.X, min.Y),
+			max, NewVector(min.X, max.Y))
+
+	case min != max:
+		vertices = append(vertices, max)
+	}
+
+	return convexCore{vertices: vertices}
+}
+
+// transformed returns the core rotated around the pivot
+// at the specified angle (in degrees) and moved after that.
+func (core convexCore) transformed(pivot, moveDiff Vector, turnDiff float64) convexCore {
+	vertices := make([]Vector, len(core.vertices))
+
+	for i, vertex := range core.vertices {
+		vertices[i] = vertex.RotateAround(turnDiff, pivot).Add(moveDiff)
+	}
+
+	return convexCore{
+		vertices: vertices,
+		radius:   core.radius,
+	}
+}
+
+// boundingBox returns the AABB of the core.
+func (core convexCore) boundingBox() *aabb {
+	min := NewVector(math.Inf(1), math.Inf(1))
+	max := NewVector(math.Inf(-1), math.Inf(-1))
+
+	for _, vertex := range core.vertices {
+		min = NewVector(math.Min(min.X, vertex.X), math.Min(min.Y, vertex.Y))
+		max = NewVector(math.Max(max.X, vertex.X), math.Max(max.Y, vertex.Y))
+	}
+
+	return &aabb{
+		min: min.Subtract(NewVector(core.radius, core.radius)),
+		max: max.Add(NewVector(core.radius, core.radius)),
+	}
+}
+
+// sweptBoundingBox returns the AABB covering the core
+// during the whole movement.
+func (core convexCore) sweptBoundingBox(pivot, moveDiff Vector, turnDiff float64) *aabb {
+	start := core.boundingBox()
+	end := core.transformed(pivot, moveDiff, turnDiff).boundingBox()
+
+	// The rotating core stays within the circle
+	// around the pivot.
+	if turnDiff != 0 {
+		reach := core.reach(pivot)
+		extents := NewVector(reach, reach)
+		start = &aabb{
+			min: pivot.Subtract(extents),
+			max: pivot.Add(extents),
+		}
+		end = &aabb{
+			min: start.min.Add(moveDiff),
+			max: start.max.Add(moveDiff),
+		}
+	}
+
+	return &aabb{
+		min: NewVector(math.Min(start.min.X, end.min.X),
+			math.Min(start.min.Y, end.min.Y)),
+		max: NewVector(math.Max(start.max.X, end.max.X),
+			math.Max(start.max.Y, end.max.Y)),
+	}
+}
+
+// reach returns the greatest distance from the pivot
+// to the points of the core.
+func (core convexCore) reach(pivot Vector) float64 {
+	reach := 0.0
+
+	for _, vertex := range core.vertices {
+		reach = math.Max(reach, Distance(pivot, vertex))
+	}
+
+	return reach + core.radius
+}
+
+// timeOfImpactCores computes the time of impact of the moving
+// core against the static one by conservative advancement.
+//
+// Returns the time of impact, the normal from the moving core
+// to the static one and the point of impact.
+func timeOfImpactCores(a convexCore, pivot, moveDiff Vector, turnDiff float64, b convexCore) (float64, Vector, Vector, bool) {
+	radii := a.radius + b.radius
+	angularBound := math.Abs(turnDiff*DegToRad) * a.reach(pivot)
+	t := 0.0
+
+	for i := 0; i < toiMaxIterations; i++ {
+		current := a.transformed(pivot, moveDiff.MultiplyByScalar(t), turnDiff*t)
+		distance, closestA, closestB := verticesDistance(current.vertices, b.vertices)
+
+		// The cores overlap.
+		if distance <= Epsilon {
+			normal, _, _ := penetrationCores(current, b)
+
+			return t, normal, closestB, true
+		}
+
+		normal := closestB.Subtract(closestA).MultiplyByScalar(1.0 / distance)
+		separation := distance - radii
+		surfaceA := closestA.Add(normal.MultiplyByScalar(a.radius))
+		surfaceB := closestB.Subtract(normal.MultiplyByScalar(b.radius))
+		point := surfaceA.Add(surfaceB).MultiplyByScalar(0.5)
+
+		if separation <= TOITolerance {
+			return t, normal, point, true
+		}
+
+		// The upper bound of the speed the core
+		// approaches the other one with.
+		bound := Dot(moveDiff, normal) + angularBound
+
+		if bound <= Epsilon {
+			return 1, Zero(), Zero(), false
+		}
+
+		t += separation / bound
+
+		if t > 1 {
+			return 1, Zero(), Zero(), false
+		}
+	}
+
+	// Conservative advancement didn't converge. It's a hit
+	// only if the shapes are close enough at the last step.
+	current := a.transformed(pivot, moveDiff.MultiplyByScalar(t), turnDiff*t)
+	distance, closestA, closestB := verticesDistance(current.vertices, b.vertices)
+
+	if distance <= Epsilon {
+		normal, _, _ := penetrationCores(current, b)
+
+		return t, normal, closestB, true
+	}
+
+	if distance-radii > TOITolerance {
+		return 1, Zero(), Zero(), false
+	}
+
+	normal := closestB.Subtract(closestA).MultiplyByScalar(1.0 / distance)
+	surfaceA := closestA.Add(normal.MultiplyByScalar(a.radius))
+	surfaceB := closestB.Subtract(normal.MultiplyByScalar(b.radius))
+
+	return t, normal, surfaceA.Add(surfaceB).MultiplyByScalar(0.5), true
+}
+
+// sweep returns the first shape the given shape would touch
+// if it moved in the specified direction and rotated at the specified
+// angle (in degrees). The shapes the filter returns false for are skipped.
+// In the wrapped space the shape is also swept from its images
+// on the other sides of the space, so the shapes across
+// the edges are hit as well.
+//
+// Returns the hit shape, the fraction of the movement, the normal
+// from the shape to the hit one and the point of impact.
+func (space *Space) sweep(shape Shape, moveDiff Vector, turnDiff float64, filter func(Shape) (bool, error)) (Shape, float64, Vector, Vector, error) {
+	shapeCore := sweptCoreOf(shape)
+	pivot := shape.Center()
+	sweptBox := shapeCore.sweptBoundingBox(pivot, moveDiff, turnDiff)
+	offsets := append([]Vector{Zero()},
+		space.wrapOffsets(sweptBox.min, sweptBox.max)...)
+
+	var (
+		hitShape  Shape
+		hitNormal Vector
+		hitPoint  Vector
+	)
+
+	minTime := 1.0
+
+	for _, offset := range offsets {
+		imageCore := shapeCore.transformed(pivot, offset, 0)
+		candidates, err := space.queryAABB(
+			sweptBox.min.Add(offset), sweptBox.max.Add(offset))
+
+		if err != nil {
+			return nil, 1, Zero(), Zero(), err
+		}
+
+		for other := range candidates {
+			if other == shape {
+				continue
+			}
+
+			passed, err := filter(other)
+
+			if err != nil {
+				return nil, 1, Zero(), Zero(), err
+			}
+
+			if !passed {
+				continue
+			}
+
+			t, normal, point, hit := timeOfImpactCores(imageCore,
+				pivot.Add(offset), moveDiff, turnDiff, sweptCoreOf(other))
+
+			// The ties are broken by the insertion order.
+			if hit && (hitShape == nil || t < minTime ||
+				t == minTime && space.orderedBefore(other, hitShape)) {
+				hitShape = other
+				hitNormal = normal
+				hitPoint = point
+				minTime = t
+			}
+		}
+	}
+
+	return hitShape, minTime, hitNormal, hitPoint, nil
+}
+
+// SweepFirst returns the first shape the given shape would touch
+// if it moved in the specified direction and rotated at the specified
+// angle (in degrees) using continuous collision detection, so fast
+// shapes don't tunnel through thin ones. Sensors are ignored.
+// The user-defined shapes are swept as their bounding boxes,
+// as in TimeOfImpact. In the wrapped space the shape
+// moving across the edge hits the shapes on the other side.
+//
+// Returns the hit shape (nil if there's none), the fraction of the movement
+// before the impact and the normal from the shape to the hit one.
+func (space *Space) SweepFirst(shape Shape, moveDiff Vector, turnDiff float64) (Shape, float64, Vector, error) {
+	if shape == nil {
+		return nil, 1, Zero(), fmt.Errorf("the shape is nil")
+	}
+
+	hitShape, t, normal, _, err := space.sweep(shape, moveDiff, turnDiff,
+		func(other Shape) (bool, error) {
+			if !blocks(shape, other) {
+				return false, nil
+			}
+
+			if !space.useTags {
+				return true, nil
+			}
+
+			return shape.ShouldCollide(other)
+		})
+
+	if err != nil {
+		return nil, 1, Zero(), err
+	}
+
+	return hitShape, t, normal, nil
+}
@@ -0,0 +1,120 @@
+package cirno_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zergon321/cirno"
+)
+
+func TestTimeOfImpact(t *testing.T) {
+	bullet, err := cirno.NewCircle(cirno.NewVector(0, 0), 0.5)
+	assert.Nil(t, err)
+	wall, err := cirno.NewLine(cirno.NewVector(50, -5), cirno.NewVector(50, 5))
+	assert.Nil(t, err)
+
+	toi, normal, hit, err := cirno.TimeOfImpact(bullet,
+		cirno.NewVector(100, 0), 0, wall)
+	assert.Nil(t, err)
+	assert.True(t, hit)
+	assert.InDelta(t, 0.495, toi, 0.0001)
+	assert.True(t, normal.ApproximatelyEqual(cirno.Right()))
+
+	// The end pose doesn't overlap the wall,
+	// but the bullet passes through it.
+	bullet.Move(cirno.NewVector(100, 0))
+	overlapped, err := cirno.ResolveCollision(bullet, wall, false)
+	assert.Nil(t, err)
+	assert.False(t, overlapped)
+	bullet.Move(cirno.NewVector(-100, 0))
+
+	_, _, hit, err = cirno.TimeOfImpact(bullet,
+		cirno.NewVector(-100, 0), 0, wall)
+	assert.Nil(t, err)
+	assert.False(t, hit)
+
+	_, _, hit, err = cirno.TimeOfImpact(bullet,
+		cirno.NewVector(100, 20), 0, wall)
+	assert.Nil(t, err)
+	assert.False(t, hit)
+}
+
+func TestTimeOfImpactRotation(t *testing.T) {
+	bar, err := cirno.NewRectangle(cirno.NewVector(0, 0), 10, 1, 0)
+	assert.Nil(t, err)
+	post, err := cirno.NewCircle(cirno.NewVector(0, 4), 0.5)
+	assert.Nil(t, err)
+
+	toi, _, hit, err := cirno.TimeOfImpact(bar, cirno.Zero(), 180, post)
+	assert.Nil(t, err)
+	assert.True(t, hit)
+	assert.True(t, toi > 0.3 && toi < 0.5)
+
+	bar.Rotate(180 * toi)
+	overlapped, err := cirno.ResolveCollision(bar, post, false)
+	assert.Nil(t, err)
+	assert.False(t, overlapped)
+	bar.Rotate(1)
+	overlapped, err = cirno.ResolveCollision(bar, post, false)
+	assert.Nil(t, err)
+	assert.True(t, overlapped)
+}
+
+func TestSweepFirst(t *testing.T) {
+	space, err := cirno.NewSpace(5, 1, 200, 200,
+		cirno.NewVector(-100, -100), cirno.NewVector(100, 100), false)
+	assert.Nil(t, err)
+	bullet, err := cirno.NewLine(cirno.NewVector(-80, 0), cirno.NewVector(-78, 0))
+	assert.Nil(t, err)
+	near, err := cirno.NewLine(cirno.NewVector(0, -10), cirno.NewVector(0, 10))
+	assert.Nil(t, err)
+	far, err := cirno.NewLine(cirno.NewVector(50, -10), cirno.NewVector(50, 10))
+	assert.Nil(t, err)
+	aside, err := cirno.NewCircle(cirno.NewVector(-40, 40), 5)
+	assert.Nil(t, err)
+
+	err = space.Add(bullet, near, far, aside)
+	assert.Nil(t, err)
+
+	shape, toi, normal, err := space.SweepFirst(bullet, cirno.NewVector(160, 0), 0)
+	assert.Nil(t, err)
+	assert.Equal(t, near, shape)
+	assert.InDelta(t, 0.4875, toi, 0.0001)
+	assert.True(t, normal.ApproximatelyEqual(cirno.Right()))
+
+	shape, toi, _, err = space.SweepFirst(bullet, cirno.NewVector(0, 50), 0)
+	assert.Nil(t, err)
+	assert.Nil(t, shape)
+	assert.Equal(t, 1.0, toi)
+}
+
+func TestSweepFirstUserDefinedShape(t *testing.T) {
+	space, err := cirno.NewSpace(5, 1, 200, 200,
+		cirno.NewVector(-100, -100), cirno.NewVector(100, 100), false)
+	assert.Nil(t, err)
+	p := &point{position: cirno.NewVector(-80, 0)}
+	wall, err := cirno.NewLine(cirno.NewVector(0, -10), cirno.NewVector(0, 10))
+	assert.Nil(t, err)
+	d := &dot{point{position: cirno.NewVector(40, 0)}}
+
+	err = space.Add(p, wall, d)
+	assert.Nil(t, err)
+
+	// The user-defined shapes are swept
+	// as their bounding boxes.
+	shape, toi, normal, err := space.SweepFirst(p, cirno.NewVector(160, 0), 0)
+	assert.Nil(t, err)
+	assert.Equal(t, wall, shape)
+	assert.InDelta(t, 0.5, toi, 0.0001)
+	assert.True(t, normal.ApproximatelyEqual(cirno.Right()))
+
+	shape, toi, _, err = space.SweepFirst(wall, cirno.NewVector(50, 0), 0)
+	assert.Nil(t, err)
+	assert.Equal(t, d, shape)
+	assert.InDelta(t, 0.8, toi, 0.0001)
+
+	toi, _, hit, err := cirno.TimeOfImpact(p, cirno.NewVector(0, 50), 0, d)
+	assert.Nil(t, err)
+	assert.False(t, hit)
+	assert.Equal(t, 1.0, toi)
+}
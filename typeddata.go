@@ -0,0 +1,55 @@
+package cirno
+
+// DataOf returns the data assigned to the shape
+// if it's of the specified type. Otherwise
+// the zero value and false are returned.
+func DataOf[T any](shape Shape) (T, bool) {
+	var zero T
+
+	if shape == nil {
+		return zero, false
+	}
+
+	value, ok := shape.Data().(T)
+
+	return value, ok
+}
+
+// ShapesData returns the data of the specified type assigned
+// to the shapes in the order of their insertion in the space.
+// The shapes with the data of the other types are skipped.
+func ShapesData[T any](space *Space, shapes Shapes) []T {
+	values := make([]T, 0, len(shapes))
+
+	for _, shape := range space.Ordered(shapes) {
+		if value, ok := DataOf[T](shape); ok {
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
+
+// CollidingWithData returns the data of the specified type
+// assigned to the shapes colliding with the given shape.
+func CollidingWithData[T any](space *Space, shape Shape) ([]T, error) {
+	shapes, err := space.CollidingWith(shape)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ShapesData[T](space, shapes), nil
+}
+
+// CollidedByData returns the data of the specified type
+// assigned to the shapes collided by the given shape.
+func CollidedByData[T any](space *Space, shape Shape) ([]T, error) {
+	shapes, err := space.CollidedBy(shape)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ShapesData[T](space, shapes), nil
+}
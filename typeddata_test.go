@@ -0,0 +1,48 @@
+package cirno_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zergon321/cirno"
+)
+
+type entity struct {
+	name string
+}
+
+func TestTypedData(t *testing.T) {
+	space, err := cirno.NewSpace(2, 2, 40, 40, cirno.NewVector(-20, -20),
+		cirno.NewVector(20, 20), false)
+	assert.Nil(t, err)
+
+	player, err := cirno.NewCircle(cirno.NewVector(0, 0), 2)
+	assert.Nil(t, err)
+	player.SetData(&entity{name: "player"})
+	enemy, err := cirno.NewCircle(cirno.NewVector(1, 0), 1)
+	assert.Nil(t, err)
+	enemy.SetData(&entity{name: "enemy"})
+	coin, err := cirno.NewCircle(cirno.NewVector(-1, 0), 1)
+	assert.Nil(t, err)
+	coin.SetData(10)
+	err = space.Add(player, enemy, coin)
+	assert.Nil(t, err)
+
+	value, ok := cirno.DataOf[*entity](player)
+	assert.True(t, ok)
+	assert.Equal(t, "player", value.name)
+	_, ok = cirno.DataOf[*entity](coin)
+	assert.False(t, ok)
+	_, ok = cirno.DataOf[int](nil)
+	assert.False(t, ok)
+
+	entities, err := cirno.CollidingWithData[*entity](space, player)
+	assert.Nil(t, err)
+	assert.Len(t, entities, 1)
+	assert.Equal(t, "enemy", entities[0].name)
+
+	coins, err := cirno.CollidedByData[int](space, player)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{10}, coins)
+}
@@ -43,8 +43,165 @@ func SquaredDistance(a, b Vector) float64 {
 	return b.Subtract(a).SquaredMagnitude()
 }
 
+// signedArea returns the signed area of the polygon
+// formed by the vertices. The area is positive if
+// the vertices are in counter-clockwise order.
+func signedArea(vertices []Vector) float64 {
+	area := 0.0
+
+	for i := range vertices {
+		area += Cross(vertices[i], vertices[(i+1)%len(vertices)])
+	}
+
+	return area / 2.0
+}
+
+// closestPointOnSegment returns the point of the
+// segment from p to q closest to the given point.
+func closestPointOnSegment(p, q, point Vector) Vector {
+	pq := q.Subtract(p)
+	sqrLength := pq.SquaredMagnitude()
+
+	if sqrLength < Epsilon*Epsilon {
+		return p
+	}
+
+	t := Dot(point.Subtract(p), pq) / sqrLength
+
+	if t < 0.0 {
+		t = 0.0
+	} else if t > 1.0 {
+		t = 1.0
+	}
+
+	return p.Add(pq.MultiplyByScalar(t))
+}
+
+// projectVertices returns the minimum and the maximum
+// of the vertices projections onto the axis.
+func projectVertices(vertices []Vector, axis Vector) (float64, float64) {
+	min := math.Inf(1)
+	max := math.Inf(-1)
+
+	for _, vertex := range vertices {
+		projection := Dot(vertex, axis)
+		min = math.Min(min, projection)
+		max = math.Max(max, projection)
+	}
+
+	return min, max
+}
+
+// edgeAxes returns the normals of all the edges
+// of the convex set of vertices.
+func edgeAxes(vertices []Vector) []Vector {
+	axes := make([]Vector, 0, len(vertices))
+
+	for i := range vertices {
+		edge := vertices[(i+1)%len(vertices)].Subtract(vertices[i])
+		axis, err := edge.PerpendicularClockwise().Normalize()
+
+		if err != nil {
+			continue
+		}
+
+		axes = append(axes, axis)
+	}
+
+	// A segment has no area, so its own
+	// direction is a separating axis candidate too.
+	if len(vertices) == 2 {
+		axis, err := vertices[1].Subtract(vertices[0]).Normalize()
+
+		if err == nil {
+			axes = append(axes, axis)
+		}
+	}
+
+	return axes
+}
+
+// verticesOverlap returns true if the convex sets of
+// vertices overlap according to the separating axis theorem.
+func verticesOverlap(a, b []Vector) bool {
+	axes := append(edgeAxes(a), edgeAxes(b)...)
+
+	for _, axis := range axes {
+		aMin, aMax := projectVertices(a, axis)
+		bMin, bMax := projectVertices(b, axis)
+
+		if aMax < bMin || bMax < aMin {
+			return false
+		}
+	}
+
+	return true
+}
+
+// closestPoints returns the pair of the closest points
+// of two non-overlapping convex sets of vertices.
+func closestPoints(a, b []Vector) (Vector, Vector) {
+	var (
+		closestA           Vector
+		closestB           Vector
+		minSquaredDistance = math.Inf(1)
+	)
+
+	for i := range b {
+		p := b[i]
+		q := b[(i+1)%len(b)]
+
+		for _, vertex := range a {
+			candidate := closestPointOnSegment(p, q, vertex)
+			sqrDistance := SquaredDistance(candidate, vertex)
+
+			if sqrDistance < minSquaredDistance {
+				closestA = vertex
+				closestB = candidate
+				minSquaredDistance = sqrDistance
+			}
+		}
+	}
+
+	for i := range a {
+		p := a[i]
+		q := a[(i+1)%len(a)]
+
+		for _, vertex := range b {
+			candidate := closestPointOnSegment(p, q, vertex)
+			sqrDistance := SquaredDistance(candidate, vertex)
+
+			if sqrDistance < minSquaredDistance {
+				closestA = candidate
+				closestB = vertex
+				minSquaredDistance = sqrDistance
+			}
+		}
+	}
+
+	return closestA, closestB
+}
+
+// verticesDistance returns the distance between two
+// convex sets of vertices and their closest points.
+//
+// The distance is 0 if the sets overlap.
+func verticesDistance(a, b []Vector) (float64, Vector, Vector) {
+	closestA, closestB := closestPoints(a, b)
+
+	if (len(a) > 1 || len(b) > 1) && verticesOverlap(a, b) {
+		return 0, closestA, closestB
+	}
+
+	return Distance(closestA, closestB), closestA, closestB
+}
+
 // Approximate attempts to move the shape in the specified direction
 // to detect the closest point until the shape collides other shapes.
+// Sensors don't stop the shape. The moved copy of the shape is tested,
+// so the shape itself is not changed. The user-defined shapes which
+// don't implement Cloner are tested as the circles containing
+// them at any angle.
 func Approximate(shape Shape, moveDiff Vector, turnDiff float64, shapes Shapes, intensity int, useTags bool) (Vector, float64, Shape, error) {
 	if shape == nil {
 		return Zero(), -1, nil,
@@ -63,6 +220,15 @@ func Approximate(shape Shape, moveDiff Vector, turnDiff float64, shapes Shapes,
 			fmt.Errorf("the value of intensity must be positive")
 	}
 
+	ghost, err := ghostOf(shape)
+
+	if err != nil {
+		return Zero(), -1, nil, err
+	}
+
+	// The shapes are checked in the order of insertion,
+	// so the same shape is found for the same input.
+	ordered := shapes.OrderedItems()
 	step := 1.0 / float64(intensity)
 	originalPos := shape.Center()
 	originalAngle := shape.Angle()
@@ -72,17 +238,21 @@ func Approximate(shape Shape, moveDiff Vector, turnDiff float64, shapes Shapes,
 	for i := 0; i < intensity; i++ {
 		currentPos := prevPos.Add(moveDiff.MultiplyByScalar(step))
 		currentAngle := prevAngle + turnDiff*step
-		shape.SetPosition(currentPos)
-		shape.SetAngle(currentAngle)
+		ghost.SetPosition(currentPos)
+		ghost.SetAngle(currentAngle)
 		collisionFound := false
 
-		for other := range shapes {
-			id := shape.TypeName() + "_" + other.TypeName()
+		for _, other := range ordered {
+			// Sensors don't block the movement.
+			if !blocks(ghost, other) {
+				continue
+			}
+
+			line, shapeIsLine := ghost.(*Line)
+			otherLine, otherIsLine := other.(*Line)
 
 			// Make sure lines will collide.
-			if id == "Line_Line" {
-				line := shape.(*Line)
-				otherLine := other.(*Line)
+			if shapeIsLine && otherIsLine {
 				// Compare line tags.
 				shouldCollide, err := line.ShouldCollide(otherLine)
 
@@ -112,7 +282,7 @@ func Approximate(shape Shape, moveDiff Vector, turnDiff float64, shapes Shapes,
 				}
 			}
 
-			overlapped, err := ResolveCollision(shape, other, useTags)
+			overlapped, err := ResolveCollision(ghost, other, useTags)
 
 			if err != nil {
 				return Zero(), -1, nil, err
@@ -134,9 +304,6 @@ func Approximate(shape Shape, moveDiff Vector, turnDiff float64, shapes Shapes,
 		prevAngle = currentAngle
 	}
 
-	shape.SetPosition(originalPos)
-	shape.SetAngle(originalAngle)
-
 	if math.IsNaN(prevPos.X) || math.IsNaN(prevPos.Y) {
 		return Zero(), 0.0, nil, fmt.Errorf("couldn't approximate the shape")
 	}
@@ -167,6 +334,7 @@ func AdjustAngle(angle float64) float64 {
 
 // linesWouldCollide returns true if the first line moved in the specified
 // direction from its original position would collide the second line on the way.
+// The lines are not changed.
 func linesWouldCollide(originalPos Vector, originalAngle float64, moveDiff Vector, turnDiff float64, line, otherLine *Line) (bool, error) {
 	if line == nil {
 		return false, fmt.Errorf("the first line is nil")
@@ -176,38 +344,32 @@ func linesWouldCollide(originalPos Vector, originalAngle float64, moveDiff Vecto
 		return false, fmt.Errorf("the second line is nil")
 	}
 
-	tmpPos := line.Center()
-	tmpAngle := line.Angle()
-	line.SetPosition(originalPos)
-	line.SetAngle(originalAngle)
-	origP := line.p
-	origQ := line.q
-
-	otherTmpPos := otherLine.Center()
-	otherTmpAngle := otherLine.Angle()
-	otherLine.Move(moveDiff.MultiplyByScalar(-1))
-	otherLine.Rotate(-turnDiff)
-	movedP := otherLine.p
-	movedQ := otherLine.q
-	otherLine.SetPosition(otherTmpPos)
-	otherLine.SetAngle(otherTmpAngle)
+	original := &Line{p: line.p, q: line.q, angle: line.angle}
+	original.SetPosition(originalPos)
+	original.SetAngle(originalAngle)
+
+	// The other line moved backwards
+	// relative to the first line.
+	moved := &Line{p: otherLine.p, q: otherLine.q, angle: otherLine.angle}
+	moved.Move(moveDiff.MultiplyByScalar(-1))
+	moved.Rotate(-turnDiff)
 
 	pp := &Line{
-		p: movedP,
+		p: moved.p,
 		q: otherLine.p,
 	}
 	qq := &Line{
-		p: movedQ,
+		p: moved.q,
 		q: otherLine.q,
 	}
 
-	ppIntersects, err := IntersectionLineToLine(pp, line)
+	ppIntersects, err := IntersectionLineToLine(pp, original)
 
 	if err != nil {
 		return false, err
 	}
 
-	qqIntersects, err := IntersectionLineToLine(qq, line)
+	qqIntersects, err := IntersectionLineToLine(qq, original)
 
 	if err != nil {
 		return false, err
@@ -218,15 +380,12 @@ func linesWouldCollide(originalPos Vector, originalAngle float64, moveDiff Vecto
 		return true, nil
 	}
 
-	line.SetPosition(tmpPos)
-	line.SetAngle(tmpAngle)
-
 	pp = &Line{
-		p: origP,
+		p: original.p,
 		q: line.p,
 	}
 	qq = &Line{
-		p: origQ,
+		p: original.q,
 		q: line.q,
 	}
 
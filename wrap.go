@@ -0,0 +1,186 @@
+package cirno
+
+import (
+	"fmt"
+	"math"
+)
+
+// Cloner is implemented by the user-defined shapes
+// which can be copied. The space needs to copy the shapes
+// to find collisions across the edges of the wrapped space
+// and to take snapshots. The predictive queries test the moved
+// copies of the shapes, the shapes which can't be copied are
+// replaced by the circles containing them at any angle.
+type Cloner interface {
+	Clone() Shape
+}
+
+// cloneShape returns the copy of the shape
+// which doesn't belong to any space.
+func cloneShape(shape Shape) (Shape, error) {
+	switch original := shape.(type) {
+	case *Circle:
+		circle := *original
+		circle.treeNodes = []*quadTreeNode{}
+
+		return &circle, nil
+
+	case *Line:
+		line := *original
+		line.treeNodes = []*quadTreeNode{}
+
+		return &line, nil
+
+	case *Rectangle:
+		rect := *original
+		rect.treeNodes = []*quadTreeNode{}
+
+		return &rect, nil
+
+	case *Polygon:
+		polygon := *original
+		polygon.vertices = make([]Vector, len(original.vertices))
+		copy(polygon.vertices, original.vertices)
+		polygon.treeNodes = []*quadTreeNode{}
+
+		return &polygon, nil
+
+	case *Capsule:
+		capsule := *original
+		capsule.treeNodes = []*quadTreeNode{}
+
+		return &capsule, nil
+	}
+
+	if cloner, ok := shape.(Cloner); ok {
+		return cloner.Clone(), nil
+	}
+
+	return nil, fmt.Errorf(
+		"the shape of type '%s' cannot be copied", shape.TypeName())
+}
+
+// canClone returns true if the shape can be copied.
+func canClone(shape Shape) bool {
+	switch shape.(type) {
+	case *Circle, *Line, *Rectangle, *Polygon, *Capsule, Cloner:
+		return true
+	}
+
+	return false
+}
+
+// ghostOf returns the copy of the shape which can be moved
+// without changing the shape. If the shape can't be copied,
+// the circle around its center containing its bounding box
+// is returned, so it contains the shape at any angle.
+func ghostOf(shape Shape) (Shape, error) {
+	if canClone(shape) {
+		return cloneShape(shape)
+	}
+
+	min, max := shape.GetBoundingBox()
+	center := shape.Center()
+	radius := 0.0
+
+	for _, corner := range []Vector{min, max,
+		NewVector(min.X, max.Y), NewVector(max.X, min.Y)} {
+		radius = math.Max(radius, Distance(center, corner))
+	}
+
+	ghost := &Circle{
+		center: center,
+		radius: radius,
+	}
+	ghost.treeNodes = []*quadTreeNode{}
+	ghost.SetIdentity(shape.GetIdentity())
+	ghost.SetMask(shape.GetMask())
+	ghost.SetSensor(shape.IsSensor())
+
+	return ghost, nil
+}
+
+// extendWrapBox extends the area covered by the shapes
+// of the wrapped space to contain the shape.
+//
+// The area is never shrunk until the space is rebuilt.
+func (space *Space) extendWrapBox(shape Shape) {
+	box := boundingBoxOf(shape)
+
+	if space.wrapBox == nil {
+		space.wrapBox = box
+
+		return
+	}
+
+	space.wrapBox = space.wrapBox.union(box)
+}
+
+// resetWrapBox computes the area covered by
+// the shapes of the wrapped space from scratch.
+func (space *Space) resetWrapBox() {
+	space.wrapBox = nil
+
+	if space.boundsPolicy != BoundsWrap {
+		return
+	}
+
+	for shape := range space.shapes {
+		space.extendWrapBox(shape)
+	}
+}
+
+// wrapOffsets returns the offsets the box should be moved by
+// to overlap the shapes across the edges of the wrapped space.
+//
+// The zero offset is not included.
+func (space *Space) wrapOffsets(min, max Vector) []Vector {
+	if space.boundsPolicy != BoundsWrap || space.wrapBox == nil {
+		return nil
+	}
+
+	size := space.max.Subtract(space.min)
+	area := space.wrapBox
+	iMin := int(math.Ceil((area.min.X - max.X) / size.X))
+	iMax := int(math.Floor((area.max.X - min.X) / size.X))
+	jMin := int(math.Ceil((area.min.Y - max.Y) / size.Y))
+	jMax := int(math.Floor((area.max.Y - min.Y) / size.Y))
+	offsets := []Vector{}
+
+	for i := iMin; i <= iMax; i++ {
+		for j := jMin; j <= jMax; j++ {
+			if i == 0 && j == 0 {
+				continue
+			}
+
+			offsets = append(offsets, NewVector(
+				float64(i)*size.X, float64(j)*size.Y))
+		}
+	}
+
+	return offsets
+}
+
+// imagesOf returns the shape and its copies moved by the offsets
+// needed to find collisions across the edges of the wrapped space.
+//
+// If the space is not wrapped, only the shape itself is returned.
+func (space *Space) imagesOf(shape Shape) ([]Shape, error) {
+	min, max := shape.GetBoundingBox()
+	offsets := space.wrapOffsets(min, max)
+	images := make([]Shape, 0, len(offsets)+1)
+	images = append(images, shape)
+
+	for _, offset := range offsets {
+		image, err := cloneShape(shape)
+
+		if err != nil {
+			return nil, err
+		}
+
+		image.Move(offset)
+		images = append(images, image)
+	}
+
+	return images, nil
+}
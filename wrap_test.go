@@ -0,0 +1,121 @@
+package cirno_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zergon321/cirno"
+)
+
+func TestWrappedSpace(t *testing.T) {
+	space, err := cirno.NewSpace(3, 2, 256, 256, cirno.NewVector(0, 0),
+		cirno.NewVector(100, 100), false, cirno.WithBoundsPolicy(cirno.BoundsWrap))
+	assert.Nil(t, err)
+
+	right, err := cirno.NewCircle(cirno.NewVector(99, 50), 3)
+	assert.Nil(t, err)
+	left, err := cirno.NewCircle(cirno.NewVector(1, 50), 3)
+	assert.Nil(t, err)
+	middle, err := cirno.NewCircle(cirno.NewVector(50, 50), 2)
+	assert.Nil(t, err)
+	target, err := cirno.NewRectangle(cirno.NewVector(5, 20), 4, 4, 0)
+	assert.Nil(t, err)
+	corner, err := cirno.NewCircle(cirno.NewVector(1, 99), 1)
+	assert.Nil(t, err)
+
+	err = space.Add(right, left, middle, target, corner)
+	assert.Nil(t, err)
+
+	// Collisions across the edge.
+	collidingShapes, err := space.CollidingShapes()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(collidingShapes))
+	assert.Contains(t, collidingShapes[right], left)
+	assert.Contains(t, collidingShapes[left], right)
+
+	shapes, err := space.CollidingWith(left)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(shapes))
+	assert.Contains(t, shapes, right)
+
+	shapes, err = space.CollidedBy(right)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(shapes))
+	assert.Contains(t, shapes, left)
+
+	// The ray continues on the opposite side.
+	hit, ok, err := space.RaycastHit(cirno.NewVector(90, 20),
+		cirno.Right(), 30, 0, false)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, target, hit.Shape)
+	assert.InDelta(t, 13, hit.Distance, 0.0001)
+	assert.True(t, hit.Point.ApproximatelyEqual(cirno.NewVector(3, 20)))
+	assert.True(t, hit.Normal.ApproximatelyEqual(cirno.Left()))
+
+	// The box overlaps the shape in the opposite corner.
+	box, err := cirno.NewRectangle(cirno.NewVector(99, 1), 6, 6, 0)
+	assert.Nil(t, err)
+	shapes, err = space.Boxcast(box)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(shapes))
+	assert.Contains(t, shapes, corner)
+
+	circle, err := cirno.NewCircle(cirno.NewVector(50, 102), 4)
+	assert.Nil(t, err)
+	shapes, err = space.Circlecast(circle)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(shapes))
+	circle.SetPosition(cirno.NewVector(3, 101))
+	shapes, err = space.Circlecast(circle)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(shapes))
+	assert.Contains(t, shapes, corner)
+
+	// Update puts the shape on the opposite side.
+	middle.Move(cirno.NewVector(55, 0))
+	_, err = space.Update(middle)
+	assert.Nil(t, err)
+	assert.True(t, middle.Center().ApproximatelyEqual(cirno.NewVector(5, 50)))
+
+	shapes, err = space.CollidingWith(middle)
+	assert.Nil(t, err)
+	assert.Contains(t, shapes, left)
+}
+
+func TestWrappedSweeps(t *testing.T) {
+	space, err := cirno.NewSpace(3, 2, 100, 100, cirno.NewVector(0, 0),
+		cirno.NewVector(100, 100), false, cirno.WithBoundsPolicy(cirno.BoundsWrap))
+	assert.Nil(t, err)
+
+	bullet, err := cirno.NewCircle(cirno.NewVector(90, 50), 1)
+	assert.Nil(t, err)
+	target, err := cirno.NewCircle(cirno.NewVector(10, 50), 2)
+	assert.Nil(t, err)
+	err = space.Add(bullet, target)
+	assert.Nil(t, err)
+
+	// The bullet moving across the edge
+	// hits the target on the other side.
+	shape, toi, normal, err := space.SweepFirst(bullet, cirno.NewVector(30, 0), 0)
+	assert.Nil(t, err)
+	assert.Equal(t, target, shape)
+	assert.InDelta(t, 17.0/30.0, toi, 0.0001)
+	assert.True(t, normal.ApproximatelyEqual(cirno.Right()))
+
+	probe, err := cirno.NewCircle(cirno.NewVector(90, 50), 1)
+	assert.Nil(t, err)
+	err = space.Remove(bullet)
+	assert.Nil(t, err)
+	shape, toi, point, normal, err := space.ShapeCast(probe, cirno.Right(), 30, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, target, shape)
+	assert.InDelta(t, 17.0/30.0, toi, 0.0001)
+	assert.True(t, point.ApproximatelyEqual(cirno.NewVector(8, 50)))
+	assert.True(t, normal.ApproximatelyEqual(cirno.Left()))
+
+	shape, _, _, _, err = space.ShapeCast(probe, cirno.Up(), 30, 0)
+	assert.Nil(t, err)
+	assert.Nil(t, shape)
+}